@@ -7,18 +7,89 @@ type Aggregate struct {
 	arg Column
 	// 别名
 	alias string
+	// 只对不重复的值计算，COUNT(DISTINCT col)
+	distinct bool
 }
 
 func (a Aggregate) As(alias string) Aggregate {
-	return Aggregate{
-		fn:    a.fn,
-		arg:   a.arg,
-		alias: alias,
-	}
+	a.alias = alias
+	return a
+}
+
+// Count(C("UserId")).Distinct()
+func (a Aggregate) Distinct() Aggregate {
+	a.distinct = true
+	return a
 }
 
 func (a Aggregate) selectable() {}
 
+func (a Aggregate) expr() {}
+
+func (a Aggregate) orderBy() OrderBy {
+	a.alias = ""
+	return OrderBy{
+		expr: a,
+	}
+}
+
+func (a Aggregate) Asc() OrderBy {
+	return Asc(a)
+}
+
+func (a Aggregate) Desc() OrderBy {
+	return Desc(a)
+}
+
+// Count(C("Id")).Gt(5)，用在 HAVING 中
+func (a Aggregate) Eq(arg any) Predicate {
+	return Predicate{
+		left:  a,
+		op:    opEq,
+		right: valueOf(arg),
+	}
+}
+
+func (a Aggregate) Gt(arg any) Predicate {
+	return Predicate{
+		left:  a,
+		op:    opGt,
+		right: valueOf(arg),
+	}
+}
+
+func (a Aggregate) Lt(arg any) Predicate {
+	return Predicate{
+		left:  a,
+		op:    opLt,
+		right: valueOf(arg),
+	}
+}
+
+func (a Aggregate) NotEq(arg any) Predicate {
+	return Predicate{
+		left:  a,
+		op:    opNotEq,
+		right: valueOf(arg),
+	}
+}
+
+func (a Aggregate) GtEq(arg any) Predicate {
+	return Predicate{
+		left:  a,
+		op:    opGtEq,
+		right: valueOf(arg),
+	}
+}
+
+func (a Aggregate) LtEq(arg any) Predicate {
+	return Predicate{
+		left:  a,
+		op:    opLtEq,
+		right: valueOf(arg),
+	}
+}
+
 func Avg(col Column) Aggregate {
 	return Aggregate{
 		fn:  "AVG",
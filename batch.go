@@ -0,0 +1,151 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+
+	"gitee.com/youkelike/orm/internal/errs"
+	"gitee.com/youkelike/orm/model"
+)
+
+// Batches 按主键顺序分批遍历满足条件的数据，每批最多 size 条，
+// 用 WHERE pk > 上一批最后一条的主键 ORDER BY pk LIMIT size 的方式翻页，不用 OFFSET，表再大也不会越翻越慢。
+// fn 返回错误时停止遍历并返回这个错误。主键通过标签 `orm:"pk=true"` 或者 model.WithPrimaryKey 指定，只支持单列主键。
+// 排序和数量由分批决定，设置了 OrderBy、Offset、Limit 会返回 errs.ErrBatchOrderLimit；
+// from 是 join 时主键列用 join 中第一张 T 对应的表限定
+func (s *Selector[T]) Batches(ctx context.Context, size int, fn func(batch []*T) error) error {
+	pk, err := s.batchKey(size)
+	if err != nil {
+		return err
+	}
+
+	var last any
+	for {
+		batch, err := s.batch(ctx, s.sess, pk, last, size)
+		if err == errs.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = fn(batch); err != nil {
+			return err
+		}
+		if len(batch) < size {
+			return nil
+		}
+		last, err = s.sess.getCore().creator(s.model, batch[len(batch)-1]).Field(pk.GoName)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// BatchesTx 和 Batches 一样分批遍历，但每一批的查询和 fn 都在一个独立的事务中执行，
+// fn 返回错误时回滚当前批次的事务并停止遍历，已经提交的批次不受影响。
+// 只有 Selector 是用 *DB 创建的时候才能使用
+func (s *Selector[T]) BatchesTx(ctx context.Context, size int, opts *sql.TxOptions,
+	fn func(ctx context.Context, tx *Tx, batch []*T) error) error {
+	db, ok := s.sess.(*DB)
+	if !ok {
+		return errs.ErrBatchTxNeedDB
+	}
+	pk, err := s.batchKey(size)
+	if err != nil {
+		return err
+	}
+
+	var last any
+	for {
+		var batch []*T
+		err = db.DoTx(ctx, func(ctx context.Context, tx *Tx) error {
+			var err error
+			batch, err = s.batch(ctx, tx, pk, last, size)
+			if err == errs.ErrNoRows {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			return fn(ctx, tx, batch)
+		}, opts)
+		if err != nil {
+			return err
+		}
+		if len(batch) < size {
+			return nil
+		}
+		last, err = s.sess.getCore().creator(s.model, batch[len(batch)-1]).Field(pk.GoName)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// 校验分批参数，返回用来翻页的主键
+func (s *Selector[T]) batchKey(size int) (*model.Field, error) {
+	if size <= 0 {
+		return nil, errs.ErrInvalidBatchSize
+	}
+	if len(s.orderBy) > 0 || s.offset > 0 || s.limit > 0 {
+		return nil, errs.ErrBatchOrderLimit
+	}
+	var err error
+	s.model, err = s.r.Get(new(T))
+	if err != nil {
+		return nil, err
+	}
+	if len(s.model.PrimaryKeys) == 0 {
+		return nil, errs.ErrNoPrimaryKey
+	}
+	if len(s.model.PrimaryKeys) > 1 {
+		return nil, errs.ErrCompositeBatchKey
+	}
+	pk := s.model.PrimaryKeys[0]
+	// 指定了查询列时必须包含主键，否则拿不到下一批的起点
+	if len(s.columns) > 0 {
+		key := s.keyColumn(pk.GoName)
+		found := false
+		for _, col := range s.columns {
+			if c, ok := col.(Column); ok && c.name == pk.GoName && (c.table == nil || c.table == key.table) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errs.NewBatchKeyNotSelected(pk.GoName)
+		}
+	}
+	return pk, nil
+}
+
+// join 中两张表可能有同名的主键列，要用 T 对应的表限定
+func (s *Selector[T]) keyColumn(name string) Column {
+	if _, ok := s.table.(Join); !ok {
+		return C(name)
+	}
+	typ := reflect.TypeOf(new(T))
+	for _, t := range s.joinTables(s.table) {
+		if reflect.TypeOf(t.entity) == typ {
+			return t.C(name)
+		}
+	}
+	return C(name)
+}
+
+// 查询主键大于 last 的下一批数据，last 为 nil 时从头开始
+func (s *Selector[T]) batch(ctx context.Context, sess Session, pk *model.Field, last any, size int) ([]*T, error) {
+	sub := s.derive(s.columns...)
+	sub.sess = sess
+	sub.lock, sub.lockWait = s.lock, s.lockWait
+	key := s.keyColumn(pk.GoName)
+	if last != nil {
+		where := make([]Predicate, 0, len(s.where)+1)
+		where = append(where, s.where...)
+		sub.where = append(where, key.Gt(last))
+	}
+	sub.orderBy = []OrderBy{Asc(key)}
+	sub.limit = size
+	return sub.GetMulti(ctx)
+}
@@ -0,0 +1,236 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"gitee.com/youkelike/orm/internal/errs"
+	"gitee.com/youkelike/orm/model"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelector_Batches(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	r := model.NewRegistry()
+	_, err = r.Register(&TestModel{}, model.WithPrimaryKey("Id"))
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB, DBWithRegistry(r))
+	require.NoError(t, err)
+
+	t.Run("keyset", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "first_name"})
+		rows.AddRow(1, "Tom")
+		rows.AddRow(3, "Bob")
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM test_model WHERE age>? ORDER BY id ASC LIMIT 2;")).
+			WithArgs(18).WillReturnRows(rows)
+		rows = sqlmock.NewRows([]string{"id", "first_name"})
+		rows.AddRow(4, "Alice")
+		rows.AddRow(7, "Jerry")
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM test_model WHERE (age>?) AND (id>?) ORDER BY id ASC LIMIT 2;")).
+			WithArgs(18, int64(3)).WillReturnRows(rows)
+		rows = sqlmock.NewRows([]string{"id", "first_name"})
+		rows.AddRow(9, "Mark")
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM test_model WHERE (age>?) AND (id>?) ORDER BY id ASC LIMIT 2;")).
+			WithArgs(18, int64(7)).WillReturnRows(rows)
+
+		var names [][]string
+		err := NewSelector[TestModel](db).Where(C("Age").Gt(18)).Batches(context.Background(), 2, func(batch []*TestModel) error {
+			var ns []string
+			for _, tm := range batch {
+				ns = append(ns, tm.FirstName)
+			}
+			names = append(names, ns)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{{"Tom", "Bob"}, {"Alice", "Jerry"}, {"Mark"}}, names)
+	})
+
+	t.Run("last batch is full", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id"})
+		rows.AddRow(1)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM test_model ORDER BY id ASC LIMIT 1;")).WillReturnRows(rows)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM test_model WHERE id>? ORDER BY id ASC LIMIT 1;")).
+			WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		cnt := 0
+		err := NewSelector[TestModel](db).Select(C("Id")).Batches(context.Background(), 1, func(batch []*TestModel) error {
+			cnt += len(batch)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, cnt)
+	})
+
+	t.Run("callback error", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id"})
+		rows.AddRow(1)
+		mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+
+		err := NewSelector[TestModel](db).Batches(context.Background(), 1, func(batch []*TestModel) error {
+			return errors.New("biz error")
+		})
+		assert.Equal(t, errors.New("biz error"), err)
+	})
+
+	t.Run("primary key not selected", func(t *testing.T) {
+		err := NewSelector[TestModel](db).Select(C("Age")).Batches(context.Background(), 1, func(batch []*TestModel) error {
+			return nil
+		})
+		assert.Equal(t, errs.NewBatchKeyNotSelected("Id"), err)
+	})
+
+	t.Run("join", func(t *testing.T) {
+		t1 := TableOf(&KeyModel{}).As("t1")
+		t2 := TableOf(&TestModel{}).As("t2")
+		join := t1.Join(t2).On(t1.C("Id").Eq(t2.C("Id")))
+		rows := sqlmock.NewRows([]string{"id", "first_name"})
+		rows.AddRow(1, "Tom")
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT t2.id,t2.first_name FROM (key_model AS t1 JOIN test_model AS t2 ON t1.id=t2.id) " +
+			"ORDER BY t2.id ASC LIMIT 1;")).WillReturnRows(rows)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT t2.id,t2.first_name FROM (key_model AS t1 JOIN test_model AS t2 ON t1.id=t2.id) " +
+			"WHERE t2.id>? ORDER BY t2.id ASC LIMIT 1;")).
+			WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"id", "first_name"}))
+
+		cnt := 0
+		err := NewSelector[TestModel](db).Select(t2.C("Id"), t2.C("FirstName")).From(join).
+			Batches(context.Background(), 1, func(batch []*TestModel) error {
+				cnt += len(batch)
+				return nil
+			})
+		require.NoError(t, err)
+		assert.Equal(t, 1, cnt)
+	})
+
+	t.Run("order by or limit", func(t *testing.T) {
+		for _, s := range []*Selector[TestModel]{
+			NewSelector[TestModel](db).OrderBy(C("Age").Asc()),
+			NewSelector[TestModel](db).Limit(10),
+			NewSelector[TestModel](db).Offset(10),
+		} {
+			err := s.Batches(context.Background(), 1, func(batch []*TestModel) error {
+				return nil
+			})
+			assert.Equal(t, errs.ErrBatchOrderLimit, err)
+		}
+	})
+
+	t.Run("invalid size", func(t *testing.T) {
+		err := NewSelector[TestModel](db).Batches(context.Background(), 0, func(batch []*TestModel) error {
+			return nil
+		})
+		assert.Equal(t, errs.ErrInvalidBatchSize, err)
+	})
+
+	t.Run("no primary key", func(t *testing.T) {
+		type NoPK struct {
+			Id int64
+		}
+		err := NewSelector[NoPK](db).Batches(context.Background(), 1, func(batch []*NoPK) error {
+			return nil
+		})
+		assert.Equal(t, errs.ErrNoPrimaryKey, err)
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSelector_BatchesTx(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	r := model.NewRegistry()
+	_, err = r.Register(&TestModel{}, model.WithPrimaryKey("Id"))
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB, DBWithRegistry(r))
+	require.NoError(t, err)
+
+	mock.ExpectBegin()
+	rows := sqlmock.NewRows([]string{"id"})
+	rows.AddRow(1)
+	rows.AddRow(2)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM test_model ORDER BY id ASC LIMIT 2;")).WillReturnRows(rows)
+	mock.ExpectExec("UPDATE .*").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM test_model WHERE id>? ORDER BY id ASC LIMIT 2;")).
+		WithArgs(int64(2)).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectCommit()
+
+	err = NewSelector[TestModel](db).BatchesTx(context.Background(), 2, nil,
+		func(ctx context.Context, tx *Tx, batch []*TestModel) error {
+			return NewUpdater[TestModel](tx).Value(&TestModel{Age: 18}).Updates(C("Age")).
+				Where(C("Id").Gt(0)).Exec(ctx).Err()
+		})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	mock.ExpectBegin()
+	tx, err := db.BeginTx(context.Background(), nil)
+	require.NoError(t, err)
+	err = NewSelector[TestModel](tx).BatchesTx(context.Background(), 2, nil,
+		func(ctx context.Context, tx *Tx, batch []*TestModel) error {
+			return nil
+		})
+	assert.Equal(t, errs.ErrBatchTxNeedDB, err)
+}
+
+func TestSelector_BatchesLock(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	r := model.NewRegistry()
+	_, err = r.Register(&TestModel{}, model.WithPrimaryKey("Id"))
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB, DBWithRegistry(r))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	t.Run("batches", func(t *testing.T) {
+		mock.ExpectBegin()
+		tx, err := db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		rows := sqlmock.NewRows([]string{"id"})
+		rows.AddRow(1)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM test_model ORDER BY id ASC LIMIT 1 FOR UPDATE SKIP LOCKED;")).
+			WillReturnRows(rows)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM test_model WHERE id>? ORDER BY id ASC LIMIT 1 FOR UPDATE SKIP LOCKED;")).
+			WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		err = NewSelector[TestModel](tx).ForUpdate().SkipLocked().Batches(ctx, 1, func(batch []*TestModel) error {
+			return nil
+		})
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("batches tx", func(t *testing.T) {
+		mock.ExpectBegin()
+		rows := sqlmock.NewRows([]string{"id"})
+		rows.AddRow(1)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM test_model ORDER BY id ASC LIMIT 1 FOR UPDATE NOWAIT;")).
+			WillReturnRows(rows)
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM test_model WHERE id>? ORDER BY id ASC LIMIT 1 FOR UPDATE NOWAIT;")).
+			WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectCommit()
+
+		err = NewSelector[TestModel](db).ForUpdate().NoWait().BatchesTx(ctx, 1, nil,
+			func(ctx context.Context, tx *Tx, batch []*TestModel) error {
+				return nil
+			})
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("outside tx", func(t *testing.T) {
+		err = NewSelector[TestModel](db).ForUpdate().Batches(ctx, 1, func(batch []*TestModel) error {
+			return nil
+		})
+		assert.Equal(t, errs.ErrLockOutsideTx, err)
+	})
+}
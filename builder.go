@@ -2,6 +2,7 @@ package orm
 
 import (
 	"bytes"
+	"strings"
 
 	"gitee.com/youkelike/orm/internal/errs"
 	"gitee.com/youkelike/orm/model"
@@ -10,15 +11,64 @@ import (
 type builder struct {
 	model *model.Model
 	r     model.Registry
-	// dialect Dialect
-	// core
+	// 只有全文检索这种嵌在条件里、写法又因方言而异的表达式用到，其他地方由调用方传入方言
+	dialect Dialect
 
 	sb   bytes.Buffer
 	args []any
+	// 可以当作列引用的 select 别名，只在生成 HAVING 和 ORDER BY 时设置
+	aliases map[string]bool
+	// 语句执行期间缓存 Build 的结果，执行完就清掉，不会因为之后修改了查询而拿到过期的语句
+	memo    *Query
+	memoing bool
 
 	quoter byte
 }
 
+// 每次 Build 都从空的 sb、args 开始，同一个查询 Build 多少次结果都一样
+func (b *builder) reset() {
+	b.sb.Reset()
+	b.args = nil
+}
+
+// 生成完整的语句后调用，执行期间会记下结果
+func (b *builder) done() *Query {
+	q := &Query{
+		SQL:  b.dialect.rebind(b.sb.String()),
+		Args: b.args,
+	}
+	if b.memoing {
+		b.memo = q
+	}
+	return q
+}
+
+// 复制除了生成过程中的状态以外的部分
+func (b *builder) clone() builder {
+	return builder{
+		model:   b.model,
+		r:       b.r,
+		dialect: b.dialect,
+		quoter:  b.quoter,
+	}
+}
+
+// 实现了 memoizer 的 Builder 在语句执行期间只生成一次 sql
+type memoizer interface {
+	// 开始缓存 Build 的结果，返回的函数用来停止缓存并清掉结果
+	memoize() func()
+}
+
+// 中间件里可能用同一个 Builder 再执行一次，这时会嵌套调用，
+// 所以清理的时候恢复到调用前的状态，而不是直接清空
+func (b *builder) memoize() func() {
+	memoing, memo := b.memoing, b.memo
+	b.memoing = true
+	return func() {
+		b.memoing, b.memo = memoing, memo
+	}
+}
+
 func (b *builder) quote(name string) {
 	b.sb.WriteByte(b.quoter)
 	b.sb.WriteString(name)
@@ -29,6 +79,10 @@ func (b *builder) buildColumn(c Column) error {
 	switch table := c.table.(type) {
 	case nil:
 		fd, ok := b.model.FieldMap[c.name]
+		if !ok && b.aliases[c.name] {
+			b.sb.WriteString(c.name)
+			return nil
+		}
 		if !ok {
 			return errs.NewUnknownField(c.name)
 		}
@@ -37,10 +91,6 @@ func (b *builder) buildColumn(c Column) error {
 			b.sb.WriteString(" AS ")
 			b.sb.WriteString(c.alias)
 		}
-		if c.order != "" {
-			b.sb.WriteString(" ")
-			b.sb.WriteString(c.order)
-		}
 	case Table:
 		m, err := b.r.Get(table.entity)
 		if err != nil {
@@ -62,9 +112,18 @@ func (b *builder) buildColumn(c Column) error {
 			b.sb.WriteString(" AS ")
 			b.sb.WriteString(c.alias)
 		}
-		if c.order != "" {
-			b.sb.WriteString(" ")
-			b.sb.WriteString(c.order)
+	case derivedTable:
+		sub := table.derived()
+		name, err := sub.column(c.name)
+		if err != nil {
+			return err
+		}
+		b.sb.WriteString(sub.as)
+		b.sb.WriteString(".")
+		b.sb.WriteString(name)
+		if c.alias != "" {
+			b.sb.WriteString(" AS ")
+			b.sb.WriteString(c.alias)
 		}
 	default:
 		return errs.NewUnsupportTable(table)
@@ -88,11 +147,11 @@ func (b *builder) buildPredicate(p Predicate) error {
 		}
 	}
 
-	if p.op == opNot || p.op == opAnd || p.op == opOr {
+	if p.op.keyword() {
 		b.sb.WriteString(" ")
 	}
 	b.sb.WriteString(p.op.String())
-	if p.op == opNot || p.op == opAnd || p.op == opOr {
+	if p.op.keyword() {
 		b.sb.WriteString(" ")
 	}
 
@@ -113,6 +172,151 @@ func (b *builder) buildPredicate(p Predicate) error {
 	return nil
 }
 
+// 生成 FROM 后面的表，table 为 nil 时使用模型对应的表，方言不支持的 join 类型会返回错误
+func (b *builder) buildTable(dialect Dialect, table TableReference) error {
+	switch t := table.(type) {
+	case nil:
+		b.sb.WriteString(b.model.TableName)
+	case Table:
+		m, err := b.r.Get(t.entity)
+		if err != nil {
+			return err
+		}
+		b.sb.WriteString(m.TableName)
+		if t.alias != "" {
+			b.sb.WriteString(" AS ")
+			b.sb.WriteString(t.alias)
+		}
+	case Join:
+		typ := t.typ
+		if t.lateral {
+			if _, ok := t.right.(derivedTable); !ok {
+				return errs.ErrLateralNotSubquery
+			}
+			typ += " LATERAL"
+		}
+		if !dialect.supportJoin(t.typ, t.lateral) {
+			return errs.NewUnsupportedJoin(typ)
+		}
+		b.sb.WriteString("(")
+		err := b.buildTable(dialect, t.left)
+		if err != nil {
+			return err
+		}
+		b.sb.WriteString(" " + typ + " ")
+		err = b.buildTable(dialect, t.right)
+		if err != nil {
+			return err
+		}
+
+		if len(t.using) > 0 {
+			b.sb.WriteString(" USING (")
+			for i, col := range t.using {
+				if i > 0 {
+					b.sb.WriteString(",")
+				}
+				err := b.buildColumn(Column{name: col})
+				if err != nil {
+					return err
+				}
+			}
+			b.sb.WriteString(")")
+		}
+
+		if len(t.on) > 0 {
+			// 和 WHERE 一样跳过空条件
+			p := And(t.on...)
+			if p.empty() {
+				return errs.ErrEmptyJoinOn
+			}
+			b.sb.WriteString(" ON ")
+			if err := b.buildPredicate(p); err != nil {
+				return err
+			}
+		}
+		b.sb.WriteString(")")
+	case derivedTable:
+		sub := t.derived()
+		res, err := sub.src.Build()
+		if err != nil {
+			return err
+		}
+
+		b.addArgs(res.Args...)
+		b.sb.WriteString("(")
+		b.sb.WriteString(strings.Trim(res.SQL, ";"))
+		b.sb.WriteString(")")
+		b.sb.WriteString(" AS ")
+		b.sb.WriteString(sub.as)
+	default:
+		return errs.NewUnsupportTable(table)
+	}
+	return nil
+}
+
+func (b *builder) buildAggregate(a Aggregate) error {
+	b.sb.WriteString(a.fn)
+	b.sb.WriteString("(")
+	if a.distinct {
+		b.sb.WriteString("DISTINCT ")
+	}
+	if err := b.buildColumn(a.arg); err != nil {
+		return err
+	}
+	b.sb.WriteString(")")
+	if a.alias != "" {
+		b.sb.WriteString(" AS ")
+		b.sb.WriteString(a.alias)
+	}
+	return nil
+}
+
+// 生成 ORDER BY 子句，NULLS FIRST、NULLS LAST 的写法由方言决定
+func (b *builder) buildOrderBy(dialect Dialect, items []OrderBy) error {
+	if len(items) == 0 {
+		return nil
+	}
+	b.sb.WriteString(" ORDER BY ")
+	for i, item := range items {
+		if i > 0 {
+			b.sb.WriteString(",")
+		}
+		if err := dialect.buildOrderByItem(b, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 生成排序项中的表达式和排序规则，不包括 NULLS FIRST、NULLS LAST
+func (b *builder) buildOrderByExpr(item OrderBy) error {
+	if err := b.buildExpresssion(item.expr); err != nil {
+		return err
+	}
+	if item.order != "" {
+		b.sb.WriteString(" ")
+		b.sb.WriteString(item.order)
+	}
+	return nil
+}
+
+// 有多个条件时用 AND 连接起来，组合后是空条件时什么都不做，不会只生成一个 WHERE
+func (b *builder) buildWhere(ps []Predicate) error {
+	return b.buildCondition("WHERE", ps)
+}
+
+// keyword 是 WHERE 或 HAVING
+func (b *builder) buildCondition(keyword string, ps []Predicate) error {
+	p := And(ps...)
+	if p.empty() {
+		return nil
+	}
+	b.sb.WriteString(" ")
+	b.sb.WriteString(keyword)
+	b.sb.WriteString(" ")
+	return b.buildPredicate(p)
+}
+
 func (b *builder) buildExpresssion(expr Expression) error {
 	switch p := expr.(type) {
 	case nil:
@@ -124,9 +328,29 @@ func (b *builder) buildExpresssion(expr Expression) error {
 		if err != nil {
 			return err
 		}
+	case Aggregate:
+		p.alias = ""
+		return b.buildAggregate(p)
+	case FullText:
+		return b.dialect.buildFullText(b, p)
 	case value:
 		b.sb.WriteString("?")
 		b.addArgs(p.val)
+	case values:
+		// IN () 是语法错误，没有值时用 IN (NULL)，结果和空集合一样
+		if len(p.vals) == 0 {
+			b.sb.WriteString("(NULL)")
+			return nil
+		}
+		b.sb.WriteString("(")
+		for i, val := range p.vals {
+			if i > 0 {
+				b.sb.WriteString(",")
+			}
+			b.sb.WriteString("?")
+			b.addArgs(val)
+		}
+		b.sb.WriteString(")")
 	case RawExpr:
 		b.sb.WriteString("(")
 		b.sb.WriteString(p.raw)
@@ -145,3 +369,23 @@ func (b *builder) addArgs(vals ...any) error {
 	b.args = append(b.args, vals...)
 	return nil
 }
+
+// 把以字段名或列名为 key 的 map 转换成以字段为 key 的 map，
+// 同一个字段既用字段名又用列名出现时报错
+func (b *builder) fieldValues(vals map[string]any) (map[*model.Field]any, error) {
+	res := make(map[*model.Field]any, len(vals))
+	for key, val := range vals {
+		fd, ok := b.model.FieldMap[key]
+		if !ok {
+			fd, ok = b.model.ColumnMap[key]
+		}
+		if !ok {
+			return nil, errs.NewUnknownField(key)
+		}
+		if _, ok = res[fd]; ok {
+			return nil, errs.NewDuplicateField(fd.GoName)
+		}
+		res[fd] = val
+	}
+	return res, nil
+}
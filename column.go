@@ -4,7 +4,6 @@ type Column struct {
 	table TableReference
 	name  string
 	alias string
-	order string
 }
 
 // C("name")
@@ -22,16 +21,20 @@ func (c Column) As(alias string) Column {
 	}
 }
 
-func (c Column) Desc() Column {
-	return Column{
-		name:  c.name,
-		order: "DESC",
-	}
+// 保留表的限定名，join 查询中可以按右表的列排序
+func (c Column) Desc() OrderBy {
+	return Desc(c)
 }
-func (c Column) Asc() Column {
-	return Column{
-		name:  c.name,
-		order: "ASC",
+
+func (c Column) Asc() OrderBy {
+	return Asc(c)
+}
+
+// 直接用列排序时不指定排序规则，由数据库使用默认的升序
+func (c Column) orderBy() OrderBy {
+	c.alias = ""
+	return OrderBy{
+		expr: c,
 	}
 }
 
@@ -57,6 +60,45 @@ func (c Column) Lt(arg any) Predicate {
 		right: valueOf(arg),
 	}
 }
+func (c Column) NotEq(arg any) Predicate {
+	return Predicate{
+		left:  c,
+		op:    opNotEq,
+		right: valueOf(arg),
+	}
+}
+func (c Column) GtEq(arg any) Predicate {
+	return Predicate{
+		left:  c,
+		op:    opGtEq,
+		right: valueOf(arg),
+	}
+}
+func (c Column) LtEq(arg any) Predicate {
+	return Predicate{
+		left:  c,
+		op:    opLtEq,
+		right: valueOf(arg),
+	}
+}
+
+// C("name").Like("Tom%")，通配符由调用方自己加
+func (c Column) Like(arg any) Predicate {
+	return Predicate{
+		left:  c,
+		op:    opLike,
+		right: valueOf(arg),
+	}
+}
+
+// C("id").In(1, 2, 3)
+func (c Column) In(args ...any) Predicate {
+	return Predicate{
+		left:  c,
+		op:    opIn,
+		right: values{vals: args},
+	}
+}
 
 func (c Column) expr() {}
 
@@ -20,15 +20,29 @@ type core struct {
 	creator valuer.Creator
 	// 中间件
 	mdls []Middleware
+	// 游标分页时用来给游标签名的密钥
+	cursorKey []byte
+	// 方言不支持行锁时忽略 FOR UPDATE 之类的子句，而不是报错
+	ignoreLock bool
+}
+
+// 套上中间件后执行 root。执行期间 Build 的结果会被缓存，
+// 中间件和 root 都可以调用 qc.Builder.Build()，拿到的是同一条语句
+func handle(ctx context.Context, qc *QueryContext, root Handler) *QueryResult {
+	mdls := qc.Sess.getCore().mdls
+	for i := len(mdls) - 1; i >= 0; i-- {
+		root = mdls[i](root)
+	}
+	if m, ok := qc.Builder.(memoizer); ok {
+		defer m.memoize()()
+	}
+	return root(ctx, qc)
 }
 
 // 为了支持泛型，只能用函数，不能做成绑定到对象上的方法
 func get[T any](ctx context.Context, qc *QueryContext) *QueryResult {
 	root := getHandler[T]
-	for i := len(qc.Sess.getCore().mdls) - 1; i >= 0; i-- {
-		root = qc.Sess.getCore().mdls[i](root)
-	}
-	return root(ctx, qc)
+	return handle(ctx, qc, root)
 }
 
 func getHandler[T any](ctx context.Context, qc *QueryContext) *QueryResult {
@@ -65,10 +79,7 @@ func getHandler[T any](ctx context.Context, qc *QueryContext) *QueryResult {
 
 func exec(ctx context.Context, qc *QueryContext) *QueryResult {
 	root := execHandler
-	for i := len(qc.Sess.getCore().mdls) - 1; i >= 0; i-- {
-		root = qc.Sess.getCore().mdls[i](root)
-	}
-	return root(ctx, qc)
+	return handle(ctx, qc, root)
 }
 
 func execHandler(ctx context.Context, qc *QueryContext) *QueryResult {
@@ -89,10 +100,7 @@ func execHandler(ctx context.Context, qc *QueryContext) *QueryResult {
 func getMulti[T any](ctx context.Context, qc *QueryContext) *QueryResult {
 	// 把业务逻辑改造成一个 handler
 	root := getMultiHandler[T]
-	for i := len(qc.Sess.getCore().mdls) - 1; i >= 0; i-- {
-		root = qc.Sess.getCore().mdls[i](root)
-	}
-	return root(ctx, qc)
+	return handle(ctx, qc, root)
 }
 
 func getMultiHandler[T any](ctx context.Context, qc *QueryContext) *QueryResult {
@@ -115,9 +123,18 @@ func getMultiHandler[T any](ctx context.Context, qc *QueryContext) *QueryResult
 	for rows.Next() {
 		tp := new(T)
 		val := qc.Sess.getCore().creator(qc.Model, tp)
-		err = val.SetColumns(rows)
+		if err = val.SetColumns(rows); err != nil {
+			return &QueryResult{
+				Err: err,
+			}
+		}
 		tps = append(tps, tp)
 	}
+	if err = rows.Err(); err != nil {
+		return &QueryResult{
+			Err: err,
+		}
+	}
 
 	if tps == nil {
 		return &QueryResult{
@@ -130,3 +147,33 @@ func getMultiHandler[T any](ctx context.Context, qc *QueryContext) *QueryResult
 		Result: tps,
 	}
 }
+
+func iter[T any](ctx context.Context, qc *QueryContext) *QueryResult {
+	root := iterHandler[T]
+	return handle(ctx, qc, root)
+}
+
+// 只执行查询，不读取数据，*sql.Rows 交给 Iterator 管理，由调用者逐行读取并负责关闭
+func iterHandler[T any](ctx context.Context, qc *QueryContext) *QueryResult {
+	q, err := qc.Builder.Build()
+	if err != nil {
+		return &QueryResult{
+			Err: err,
+		}
+	}
+
+	rows, err := qc.Sess.queryContext(ctx, q.SQL, q.Args...)
+	if err != nil {
+		return &QueryResult{
+			Err: err,
+		}
+	}
+
+	return &QueryResult{
+		Result: &Iterator[T]{
+			rows:    rows,
+			model:   qc.Model,
+			creator: qc.Sess.getCore().creator,
+		},
+	}
+}
@@ -31,7 +31,8 @@ func (db *DB) DoTx(ctx context.Context,
 	panicked := true
 	defer func() {
 		if panicked || err != nil {
-			e := tx.Rollback()
+			// 中间件可能已经回滚了事务，这时不算回滚失败
+			e := tx.RollbackIfNotCommit()
 			err = errs.NewErrFailedToRollback(err, e, true)
 		} else {
 			err = tx.Commit()
@@ -132,3 +133,17 @@ func DBWithMiddlewares(mdls ...Middleware) DBOption {
 		d.mdls = mdls
 	}
 }
+
+// 设置游标分页的签名密钥，多个实例之间要使用相同的密钥
+func DBWithCursorKey(key []byte) DBOption {
+	return func(d *DB) {
+		d.cursorKey = key
+	}
+}
+
+// 方言不支持行锁时（例如 sqlite）忽略 Selector 上的加锁子句，默认会返回 errs.ErrUnsupportedLock
+func DBWithIgnoreUnsupportedLock() DBOption {
+	return func(d *DB) {
+		d.ignoreLock = true
+	}
+}
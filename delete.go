@@ -3,6 +3,8 @@ package orm
 import (
 	"context"
 	"database/sql"
+
+	"gitee.com/youkelike/orm/internal/errs"
 )
 
 type Deletor[T any] struct {
@@ -10,22 +12,35 @@ type Deletor[T any] struct {
 	sess Session
 
 	table string
-
-	where []Predicate
+	// 多表删除时的表，必须包含模型对应的表
+	tableRef TableReference
+
+	where   []Predicate
+	orderBy []OrderBy
+	limit   int
+	// 允许不带 WHERE 条件删除全表
+	allowFullTable bool
+	// 优化器提示，生成在 DELETE 后面
+	hint string
 }
 
 func NewDeletor[T any](sess Session) *Deletor[T] {
 	c := sess.getCore()
 	return &Deletor[T]{
 		builder: builder{
-			r:      c.r,
-			quoter: c.dialect.quoter(),
+			r:       c.r,
+			dialect: c.dialect,
+			quoter:  c.dialect.quoter(),
 		},
 		sess: sess,
 	}
 }
 
 func (d *Deletor[T]) Build() (*Query, error) {
+	if d.memo != nil {
+		return d.memo, nil
+	}
+	d.reset()
 	if d.model == nil {
 		var err error
 		d.model, err = d.r.Get(new(T))
@@ -34,30 +49,59 @@ func (d *Deletor[T]) Build() (*Query, error) {
 		}
 	}
 
-	d.sb.WriteString("DELETE FROM ")
-	if d.table == "" {
-		d.sb.WriteString(d.model.TableName)
-	} else {
-		d.sb.WriteString(d.table)
-	}
-
-	if d.where != nil {
-		d.sb.WriteString(" WHERE ")
-		p := d.where[0]
-		for i := 1; i < len(d.where); i++ {
-			p = p.And(d.where[i])
+	syntax := d.sess.getCore().dialect.mutation()
+	where := d.where
+	d.sb.WriteString("DELETE ")
+	d.buildOptimizerHint(d.sess.getCore().dialect, d.hint)
+	switch {
+	case d.tableRef != nil && syntax.inlineJoin:
+		target, err := d.mutationTarget(d.tableRef)
+		if err != nil {
+			return nil, err
 		}
-
-		if err := d.buildPredicate(p); err != nil {
+		d.sb.WriteString(target.prefix(d.model))
+		d.sb.WriteString(" FROM ")
+		if err = d.buildTable(d.sess.getCore().dialect, d.tableRef); err != nil {
+			return nil, err
+		}
+	case d.tableRef != nil:
+		if !syntax.deleteUsing {
+			return nil, errs.NewUnsupportedMutation("DELETE ... USING")
+		}
+		target, rest, on, err := d.splitMutationJoin(d.tableRef)
+		if err != nil {
 			return nil, err
 		}
+		d.sb.WriteString("FROM ")
+		if err = d.buildTable(d.sess.getCore().dialect, target); err != nil {
+			return nil, err
+		}
+		d.sb.WriteString(" USING ")
+		if err = d.buildTable(d.sess.getCore().dialect, rest); err != nil {
+			return nil, err
+		}
+		where = append(on[:len(on):len(on)], d.where...)
+	default:
+		d.sb.WriteString("FROM ")
+		if d.table == "" {
+			d.sb.WriteString(d.model.TableName)
+		} else {
+			d.sb.WriteString(d.table)
+		}
+	}
+
+	if And(d.where...).empty() && !d.allowFullTable {
+		return nil, errs.ErrNoWhere
+	}
+	if err := d.buildWhere(where); err != nil {
+		return nil, err
+	}
+	if err := d.buildOrderLimit(d.sess.getCore().dialect, d.tableRef != nil, d.orderBy, d.limit); err != nil {
+		return nil, err
 	}
 	d.sb.WriteString(";")
 
-	return &Query{
-		SQL:  d.sb.String(),
-		Args: d.args,
-	}, nil
+	return d.done(), nil
 }
 
 func (d *Deletor[T]) From(table string) *Deletor[T] {
@@ -65,11 +109,73 @@ func (d *Deletor[T]) From(table string) *Deletor[T] {
 	return d
 }
 
+// Table 指定多表删除的表，table 中必须包含模型对应的表，只会删除这张表的数据。
+// mysql 生成 DELETE a FROM a JOIN b ON ...；
+// postgres 生成 DELETE FROM a USING b WHERE ...，要求和 Updater.Table 一样；sqlite 不支持
+func (d *Deletor[T]) Table(table TableReference) *Deletor[T] {
+	d.tableRef = table
+	return d
+}
+
+// OrderBy 和 Limit 只有单表删除可以用，mysql 和 sqlite 支持，postgres 不支持
+func (d *Deletor[T]) OrderBy(items ...Orderable) *Deletor[T] {
+	d.orderBy = orderBys(items)
+	return d
+}
+
+func (d *Deletor[T]) Limit(limit int) *Deletor[T] {
+	d.limit = limit
+	return d
+}
+
+// OptimizerHint 在 DELETE 后面生成 /*+ hint */，不支持的方言会忽略
+func (d *Deletor[T]) OptimizerHint(hint string) *Deletor[T] {
+	d.hint = hint
+	return d
+}
+
+// AllowFullTable 没有 WHERE 条件时默认返回 errs.ErrNoWhere，确实要删除全表时需要显式调用这个方法
+func (d *Deletor[T]) AllowFullTable() *Deletor[T] {
+	d.allowFullTable = true
+	return d
+}
+
+// Where 会替换掉之前设置的条件，分步骤构造条件时用 AndWhere、OrWhere、WhereIf
 func (d *Deletor[T]) Where(ps ...Predicate) *Deletor[T] {
 	d.where = ps
 	return d
 }
 
+// AndWhere 追加条件，和之前的条件用 AND 连接
+func (d *Deletor[T]) AndWhere(ps ...Predicate) *Deletor[T] {
+	d.where = andWhere(d.where, ps)
+	return d
+}
+
+// OrWhere 把之前的条件作为一个整体，和 ps 用 OR 连接，ps 之间是 AND：
+// Where(a, b).OrWhere(c, d) 生成 (a AND b) OR (c AND d)
+func (d *Deletor[T]) OrWhere(ps ...Predicate) *Deletor[T] {
+	d.where = orWhere(d.where, ps)
+	return d
+}
+
+// WhereIf 在 cond 为 true 时追加条件，相当于 AndWhere
+func (d *Deletor[T]) WhereIf(cond bool, ps ...Predicate) *Deletor[T] {
+	if cond {
+		return d.AndWhere(ps...)
+	}
+	return d
+}
+
+// Clone 复制出一个独立的 Deletor，之后对任意一方调用的方法都不会影响另一方
+func (d *Deletor[T]) Clone() *Deletor[T] {
+	c := *d
+	c.builder = d.builder.clone()
+	c.where = append([]Predicate(nil), d.where...)
+	c.orderBy = append([]OrderBy(nil), d.orderBy...)
+	return &c
+}
+
 func (d *Deletor[T]) Exec(ctx context.Context) Result {
 	var err error
 	d.model, err = d.r.Get(new(T))
@@ -5,6 +5,7 @@ import (
 	"database/sql/driver"
 	"errors"
 	"fmt"
+	"regexp"
 	"testing"
 
 	"gitee.com/youkelike/orm/internal/errs"
@@ -26,6 +27,20 @@ func TestBuild(t *testing.T) {
 		wantQuery *Query
 		wantErr   error
 	}{
+		{
+			// Clone 之后修改原来的 Deletor 不影响复制出来的
+			name: "clone",
+			builder: func() QueryBuilder {
+				base := NewDeletor[TestModel](db).Where(C("Age").Lt(18))
+				c := base.Clone().AndWhere(C("FirstName").Eq("Tom"))
+				base.Where(C("Id").Eq(1)).Limit(1)
+				return c
+			}(),
+			wantQuery: &Query{
+				SQL:  "DELETE FROM test_model WHERE (age<?) AND (first_name=?);",
+				Args: []any{18, "Tom"},
+			},
+		},
 		{
 			name:    "invalid column",
 			builder: NewDeletor[TestModel](db).Where(C("FirstName").Eq("Tom").Or(C("XXX").Eq(1))),
@@ -66,34 +81,39 @@ func TestBuild(t *testing.T) {
 		{
 			name:    "empty where",
 			builder: NewDeletor[TestModel](db).Where(),
+			wantErr: errs.ErrNoWhere,
+		},
+		{
+			name:    "allow full table",
+			builder: NewDeletor[TestModel](db).AllowFullTable(),
 			wantQuery: &Query{
 				SQL: "DELETE FROM test_model;",
 			},
 		},
 		{
 			name:    "from db",
-			builder: NewDeletor[TestModel](db).From("test_db.test_model"),
+			builder: NewDeletor[TestModel](db).From("test_db.test_model").AllowFullTable(),
 			wantQuery: &Query{
 				SQL: "DELETE FROM test_db.test_model;",
 			},
 		},
 		{
 			name:    "empty from",
-			builder: NewDeletor[TestModel](db).From(""),
+			builder: NewDeletor[TestModel](db).From("").AllowFullTable(),
 			wantQuery: &Query{
 				SQL: "DELETE FROM test_model;",
 			},
 		},
 		{
 			name:    "use from",
-			builder: NewDeletor[TestModel](db).From("test_model"),
+			builder: NewDeletor[TestModel](db).From("test_model").AllowFullTable(),
 			wantQuery: &Query{
 				SQL: "DELETE FROM test_model;",
 			},
 		},
 		{
 			name:    "no from",
-			builder: NewDeletor[TestModel](db),
+			builder: NewDeletor[TestModel](db).AllowFullTable(),
 			wantQuery: &Query{
 				SQL: "DELETE FROM test_model;",
 			},
@@ -162,3 +182,125 @@ func TestDeletor_Exec(t *testing.T) {
 		})
 	}
 }
+
+func TestDeletor_Join(t *testing.T) {
+	mockDB, _, err := sqlmock.New()
+	require.NoError(t, err)
+	mysqlDB, err := OpenDB(mockDB)
+	require.NoError(t, err)
+	sqliteDB, err := OpenDB(mockDB, DBWithDialect(DialectSQLite))
+	require.NoError(t, err)
+	pgDB, err := OpenDB(mockDB, DBWithDialect(DialectPostgreSQL))
+	require.NoError(t, err)
+
+	t1 := TableOf(&TestModel{}).As("t1")
+	t2 := TableOf(&KeyModel{}).As("t2")
+	join := t1.Join(t2).On(t1.C("Id").Eq(t2.C("Id")))
+
+	testCases := []struct {
+		name      string
+		builder   QueryBuilder
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			name:    "mysql join",
+			builder: NewDeletor[TestModel](mysqlDB).Table(join).Where(t2.C("Name").Eq("Tom")),
+			wantQuery: &Query{
+				SQL:  "DELETE t1 FROM (test_model AS t1 JOIN key_model AS t2 ON t1.id=t2.id) WHERE t2.name=?;",
+				Args: []any{"Tom"},
+			},
+		},
+		{
+			name:    "mysql order by limit",
+			builder: NewDeletor[TestModel](mysqlDB).Where(C("Age").Lt(18)).OrderBy(C("Id").Desc()).Limit(10),
+			wantQuery: &Query{
+				SQL:  "DELETE FROM test_model WHERE age<? ORDER BY id DESC LIMIT 10;",
+				Args: []any{18},
+			},
+		},
+		{
+			name:    "mysql order by without verb",
+			builder: NewDeletor[TestModel](mysqlDB).OrderBy(C("Id")).Limit(10).AllowFullTable(),
+			wantQuery: &Query{
+				SQL: "DELETE FROM test_model ORDER BY id LIMIT 10;",
+			},
+		},
+		{
+			name:    "sqlite limit",
+			builder: NewDeletor[TestModel](sqliteDB).Limit(10).AllowFullTable(),
+			wantQuery: &Query{
+				SQL: "DELETE FROM test_model LIMIT 10;",
+			},
+		},
+		{
+			name:    "sqlite join",
+			builder: NewDeletor[TestModel](sqliteDB).Table(join),
+			wantErr: errs.NewUnsupportedMutation("DELETE ... USING"),
+		},
+		{
+			name:    "postgres using",
+			builder: NewDeletor[TestModel](pgDB).Table(join).Where(t2.C("Name").Eq("Tom")),
+			wantQuery: &Query{
+				SQL:  "DELETE FROM test_model AS t1 USING key_model AS t2 WHERE (t1.id=t2.id) AND (t2.name=$1);",
+				Args: []any{"Tom"},
+			},
+		},
+		{
+			name:    "mysql join optimizer hint",
+			builder: NewDeletor[TestModel](mysqlDB).Table(join).OptimizerHint("BKA(t2)").Where(t2.C("Name").Eq("Tom")),
+			wantQuery: &Query{
+				SQL:  "DELETE /*+ BKA(t2) */ t1 FROM (test_model AS t1 JOIN key_model AS t2 ON t1.id=t2.id) WHERE t2.name=?;",
+				Args: []any{"Tom"},
+			},
+		},
+		{
+			name:    "mysql optimizer hint",
+			builder: NewDeletor[TestModel](mysqlDB).OptimizerHint("MAX_EXECUTION_TIME(1000)").Where(C("Age").Lt(18)),
+			wantQuery: &Query{
+				SQL:  "DELETE /*+ MAX_EXECUTION_TIME(1000) */ FROM test_model WHERE age<?;",
+				Args: []any{18},
+			},
+		},
+		{
+			name:    "mysql and where",
+			builder: NewDeletor[TestModel](mysqlDB).Where(C("Age").Lt(18)).AndWhere(C("Id").Gt(10)).WhereIf(true, C("Id").Lt(20)),
+			wantQuery: &Query{
+				SQL:  "DELETE FROM test_model WHERE ((age<?) AND (id>?)) AND (id<?);",
+				Args: []any{18, 10, 20},
+			},
+		},
+		{
+			name:    "postgres join using",
+			builder: NewDeletor[TestModel](pgDB).Table(t1.Join(t2).Using("Id")),
+			wantErr: errs.NewUnsupportedMutation("JOIN USING"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := tc.builder.Build()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, res)
+		})
+	}
+}
+
+func TestDeletor_PostgreSQLUsing(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB, DBWithDialect(DialectPostgreSQL))
+	require.NoError(t, err)
+
+	t1 := TableOf(&TestModel{}).As("t1")
+	t2 := TableOf(&KeyModel{}).As("t2")
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM test_model AS t1 USING key_model AS t2 WHERE ((t1.id=t2.id) AND (t2.name=$1)) AND (t1.age<$2);")).
+		WithArgs("Tom", 18).WillReturnResult(driver.RowsAffected(2))
+	affected, err := NewDeletor[TestModel](db).Table(t1.Join(t2).On(t1.C("Id").Eq(t2.C("Id")))).
+		Where(t2.C("Name").Eq("Tom"), t1.C("Age").Lt(18)).Exec(context.Background()).RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), affected)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,17 +1,55 @@
 package orm
 
 import (
+	"strconv"
+	"strings"
+
 	"gitee.com/youkelike/orm/internal/errs"
 )
 
 var (
-	DialectMySQL  Dialect = mysqlDialect{}
-	DialectSQLite Dialect = sqliteDialect{}
+	DialectMySQL      Dialect = mysqlDialect{}
+	DialectSQLite     Dialect = sqliteDialect{}
+	DialectPostgreSQL Dialect = postgreDialect{}
 )
 
 type Dialect interface {
 	quoter() byte
+	// 生成 INSERT 语句的开头部分，odk 可能为 nil
+	buildInsertPrefix(b *builder, odk *Upsert)
 	buildUpsert(b *builder, odk *Upsert) error
+	// 一条语句中最多能有多少个占位符
+	maxArgs() int
+	// 多表 UPDATE、DELETE 以及 ORDER BY、LIMIT 的写法
+	mutation() mutationSyntax
+	// 生成 SELECT 的加锁子句，lock 是 UPDATE 或 SHARE，wait 是 NOWAIT、SKIP LOCKED 或者空
+	buildLock(b *builder, lock, wait string) error
+	// 是否支持 SELECT DISTINCT ON (...)
+	supportDistinctOn() bool
+	// VALUES 中是否可以用 DEFAULT 表示使用列的默认值
+	supportDefaultValue() bool
+	// 生成 ORDER BY 中的一项
+	buildOrderByItem(b *builder, item OrderBy) error
+	// 是否支持这种 join，typ 是 JOIN、LEFT JOIN、FULL JOIN 这样的关键字
+	supportJoin(typ string, lateral bool) bool
+	// 生成 FROM 的表后面的索引提示，不支持时返回错误
+	buildIndexHints(b *builder, hints []indexHint) error
+	// 生成 /*+ ... */ 形式的优化器提示，不支持时什么都不做
+	buildOptimizerHint(b *builder, hint string)
+	// 生成全文检索条件或者相关度
+	buildFullText(b *builder, f FullText) error
+	// 把生成的语句中的 ? 换成方言要求的占位符
+	rebind(query string) string
+}
+
+type mutationSyntax struct {
+	// 为 true 时 join 直接写在 UPDATE 和 DELETE ... FROM 后面（mysql），
+	// 否则写成 UPDATE ... FROM 和 DELETE ... USING（postgres）
+	inlineJoin bool
+	// 是否支持 DELETE ... USING
+	deleteUsing bool
+	// 单表 UPDATE、DELETE 是否支持 ORDER BY 和 LIMIT
+	orderLimit bool
 }
 
 type standardSQL struct {
@@ -21,7 +59,20 @@ func (s standardSQL) quoter() byte {
 	return '"'
 }
 
+func (s standardSQL) buildInsertPrefix(b *builder, odk *Upsert) {
+	b.sb.WriteString("INSERT INTO ")
+}
+
 func (s standardSQL) buildUpsert(b *builder, odk *Upsert) error {
+	// DO NOTHING 可以不指定冲突目标，表示任何唯一约束冲突都忽略
+	if len(odk.conflictColumns) == 0 {
+		if !odk.doNothing || len(odk.conflictWhere) > 0 {
+			return errs.ErrNoConflictColumns
+		}
+		b.sb.WriteString(" ON CONFLICT DO NOTHING")
+		return nil
+	}
+
 	b.sb.WriteString(" ON CONFLICT(")
 	for i, col := range odk.conflictColumns {
 		if i > 0 {
@@ -32,7 +83,16 @@ func (s standardSQL) buildUpsert(b *builder, odk *Upsert) error {
 			return err
 		}
 	}
-	b.sb.WriteString(") DO UPDATE SET ")
+	b.sb.WriteString(")")
+	if err := b.buildWhere(odk.conflictWhere); err != nil {
+		return err
+	}
+	if odk.doNothing {
+		b.sb.WriteString(" DO NOTHING")
+		return nil
+	}
+
+	b.sb.WriteString(" DO UPDATE SET ")
 	for idx, assign := range odk.assigns {
 		if idx > 0 {
 			b.sb.WriteString(",")
@@ -58,9 +118,65 @@ func (s standardSQL) buildUpsert(b *builder, odk *Upsert) error {
 			return errs.NewUnsupportedAssignable(assign)
 		}
 	}
+	return b.buildWhere(odk.where)
+}
+
+func (s standardSQL) maxArgs() int {
+	return 65535
+}
+
+func (s standardSQL) mutation() mutationSyntax {
+	return mutationSyntax{deleteUsing: true}
+}
+
+func (s standardSQL) buildLock(b *builder, lock, wait string) error {
+	b.sb.WriteString(" FOR ")
+	b.sb.WriteString(lock)
+	if wait != "" {
+		b.sb.WriteString(" ")
+		b.sb.WriteString(wait)
+	}
+	return nil
+}
+
+func (s standardSQL) supportDistinctOn() bool {
+	return true
+}
+
+func (s standardSQL) rebind(query string) string {
+	return query
+}
+
+func (s standardSQL) supportDefaultValue() bool {
+	return true
+}
+
+func (s standardSQL) buildOrderByItem(b *builder, item OrderBy) error {
+	if err := b.buildOrderByExpr(item); err != nil {
+		return err
+	}
+	if item.nulls != "" {
+		b.sb.WriteString(" NULLS ")
+		b.sb.WriteString(item.nulls)
+	}
 	return nil
 }
 
+func (s standardSQL) supportJoin(typ string, lateral bool) bool {
+	return true
+}
+
+func (s standardSQL) buildIndexHints(b *builder, hints []indexHint) error {
+	return errs.ErrUnsupportedIndexHint
+}
+
+// postgres 本身不支持优化器提示，pg_hint_plan 插件要求提示写在整条语句的最前面，这里不处理
+func (s standardSQL) buildOptimizerHint(b *builder, hint string) {}
+
+func (s standardSQL) buildFullText(b *builder, f FullText) error {
+	return errs.ErrUnsupportedFullText
+}
+
 type mysqlDialect struct {
 	standardSQL
 }
@@ -69,7 +185,100 @@ func (s mysqlDialect) quoter() byte {
 	return '`'
 }
 
+// mysql 的多表 UPDATE、DELETE 不支持 ORDER BY 和 LIMIT
+func (s mysqlDialect) mutation() mutationSyntax {
+	return mutationSyntax{inlineJoin: true, orderLimit: true}
+}
+
+func (s mysqlDialect) supportDistinctOn() bool {
+	return false
+}
+
+// mysql 没有 FULL JOIN，LATERAL 从 8.0.14 开始支持
+func (s mysqlDialect) supportJoin(typ string, lateral bool) bool {
+	return typ != "FULL JOIN"
+}
+
+// mysql 不支持 NULLS FIRST、NULLS LAST，先按 ISNULL(expr) 排序，NULL 值对应 1
+func (s mysqlDialect) buildOrderByItem(b *builder, item OrderBy) error {
+	if item.nulls != "" {
+		b.sb.WriteString("ISNULL(")
+		if err := b.buildExpresssion(item.expr); err != nil {
+			return err
+		}
+		b.sb.WriteString(")")
+		if item.nulls == "FIRST" {
+			b.sb.WriteString(" DESC")
+		}
+		b.sb.WriteString(",")
+	}
+	return b.buildOrderByExpr(item)
+}
+
+func (s mysqlDialect) buildIndexHints(b *builder, hints []indexHint) error {
+	for _, h := range hints {
+		b.sb.WriteString(" ")
+		b.sb.WriteString(h.typ)
+		b.sb.WriteString(" INDEX (")
+		for i, idx := range h.indexes {
+			if i > 0 {
+				b.sb.WriteString(",")
+			}
+			b.sb.WriteString(idx)
+		}
+		b.sb.WriteString(")")
+	}
+	return nil
+}
+
+func (s mysqlDialect) buildOptimizerHint(b *builder, hint string) {
+	b.sb.WriteString("/*+ ")
+	b.sb.WriteString(hint)
+	b.sb.WriteString(" */ ")
+}
+
+func (s mysqlDialect) buildFullText(b *builder, f FullText) error {
+	if len(f.cols) == 0 {
+		return errs.ErrMatchNoColumns
+	}
+	b.sb.WriteString("MATCH (")
+	for i, col := range f.cols {
+		if i > 0 {
+			b.sb.WriteString(",")
+		}
+		col.alias = ""
+		if err := b.buildColumn(col); err != nil {
+			return err
+		}
+	}
+	b.sb.WriteString(") AGAINST (?")
+	b.addArgs(f.text)
+	if f.mode != "" {
+		b.sb.WriteString(" ")
+		b.sb.WriteString(string(f.mode))
+	}
+	b.sb.WriteString(")")
+	return nil
+}
+
+func (s mysqlDialect) buildInsertPrefix(b *builder, odk *Upsert) {
+	if odk != nil && odk.doNothing {
+		b.sb.WriteString("INSERT IGNORE INTO ")
+		return
+	}
+	b.sb.WriteString("INSERT INTO ")
+}
+
 func (s mysqlDialect) buildUpsert(b *builder, odk *Upsert) error {
+	if len(odk.conflictWhere) > 0 {
+		return errs.NewUnsupportedUpsertClause("冲突目标条件")
+	}
+	if len(odk.where) > 0 {
+		return errs.NewUnsupportedUpsertClause("更新条件")
+	}
+	if odk.doNothing {
+		return nil
+	}
 	b.sb.WriteString(" ON DUPLICATE KEY UPDATE ")
 	for idx, assign := range odk.assigns {
 		if idx > 0 {
@@ -108,6 +317,160 @@ func (s sqliteDialect) quoter() byte {
 	return '`'
 }
 
+// SQLITE_MAX_VARIABLE_NUMBER 在 3.32.0 之前默认是 999，之后是 32766，这里按小的算
+func (s sqliteDialect) maxArgs() int {
+	return 999
+}
+
+// sqlite 3.33.0 开始支持 UPDATE ... FROM，但不支持 DELETE ... USING，
+// ORDER BY 和 LIMIT 需要编译时打开 SQLITE_ENABLE_UPDATE_DELETE_LIMIT
+func (s sqliteDialect) mutation() mutationSyntax {
+	return mutationSyntax{orderLimit: true}
+}
+
+// sqlite 没有行锁，写事务会锁住整个数据库
+func (s sqliteDialect) buildLock(b *builder, lock, wait string) error {
+	return errs.ErrUnsupportedLock
+}
+
+// sqlite 只支持整行的 DEFAULT VALUES，不能给单独的列写 DEFAULT
+func (s sqliteDialect) supportDefaultValue() bool {
+	return false
+}
+
+func (s sqliteDialect) supportDistinctOn() bool {
+	return false
+}
+
+// sqlite 从 3.39.0 开始支持 RIGHT JOIN 和 FULL JOIN，但不支持 LATERAL
+func (s sqliteDialect) supportJoin(typ string, lateral bool) bool {
+	return !lateral
+}
+
+// FTS5 的 MATCH 左边和 bm25 的参数都是和表同名的隐藏列，有别名时写成 alias.table。
+// 指定了列时用 {col ...} : (text) 的列过滤语法限定检索的列
+func (s sqliteDialect) buildFullText(b *builder, f FullText) error {
+	if f.mode == MatchQueryExpansion {
+		return errs.NewUnsupportedMatchMode(string(f.mode))
+	}
+	table, err := b.fullTextTable(f.cols)
+	if err != nil {
+		return err
+	}
+	if f.score {
+		b.sb.WriteString("-bm25(")
+		b.sb.WriteString(table)
+		b.sb.WriteString(")")
+		return nil
+	}
+	b.sb.WriteString(table)
+	b.sb.WriteString(" MATCH ?")
+	text := f.text
+	if f.mode == MatchBoolean {
+		// 布尔模式的 text 就是 FTS5 的查询表达式，要拼到列过滤的括号里，
+		// 括号不匹配的话会跑到列过滤外面去
+		if !fts5Balanced(text) {
+			return errs.NewInvalidFullTextQuery(text)
+		}
+	} else {
+		text = fts5Phrases(text)
+	}
+	if len(f.cols) == 0 {
+		b.addArgs(text)
+		return nil
+	}
+	var sb strings.Builder
+	sb.WriteString("{")
+	for i, col := range f.cols {
+		if i > 0 {
+			sb.WriteString(" ")
+		}
+		m := b.model
+		if t, ok := col.table.(Table); ok {
+			if m, err = b.r.Get(t.entity); err != nil {
+				return err
+			}
+		}
+		fd, ok := m.FieldMap[col.name]
+		if !ok {
+			return errs.NewUnknownField(col.name)
+		}
+		sb.WriteString(fd.ColName)
+	}
+	sb.WriteString("} : (")
+	sb.WriteString(text)
+	sb.WriteString(")")
+	b.addArgs(sb.String())
+	return nil
+}
+
+// 自然语言模式下 text 里的词都当作普通的词，每个词写成 FTS5 的字符串，
+// 这样 AND、OR、括号、引号之类的都不会被当成语法
+func fts5Phrases(text string) string {
+	words := strings.Fields(text)
+	for i, w := range words {
+		words[i] = `"` + strings.ReplaceAll(w, `"`, `""`) + `"`
+	}
+	return strings.Join(words, " ")
+}
+
+// 检查字符串外面的括号是否成对，字符串是否闭合，字符串中的 "" 是转义的引号
+func fts5Balanced(text string) bool {
+	depth := 0
+	quoted := false
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth < 0 {
+				return false
+			}
+		}
+	}
+	return depth == 0 && !quoted
+}
+
 type postgreDialect struct {
 	standardSQL
 }
+
+// 占位符写成 $1、$2 ...，按出现的顺序编号。
+// 子查询 Build 的时候已经编过号了，嵌到外层语句中要和外层的 ? 一起重新编号，
+// 所以已有的 $n 也当作占位符处理；引号中的字符串和标识符原样保留
+func (s postgreDialect) rebind(query string) string {
+	var sb strings.Builder
+	sb.Grow(len(query) + 16)
+	n := 0
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			n++
+			sb.WriteString("$")
+			sb.WriteString(strconv.Itoa(n))
+			continue
+		case c == '$' && i+1 < len(query) && query[i+1] >= '0' && query[i+1] <= '9':
+			for i+1 < len(query) && query[i+1] >= '0' && query[i+1] <= '9' {
+				i++
+			}
+			n++
+			sb.WriteString("$")
+			sb.WriteString(strconv.Itoa(n))
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
@@ -0,0 +1,142 @@
+package orm
+
+import (
+	"context"
+	"reflect"
+
+	"gitee.com/youkelike/orm/internal/errs"
+	"gitee.com/youkelike/orm/model"
+)
+
+// 这里的方法都依赖模型的主键，主键通过标签 `orm:"pk=true"` 或者 model.WithPrimaryKey 指定，
+// 它们只是 Selector、Inserter、Updater、Deletor 的简单组合，可以用在 DB 和 Tx 上
+
+// Get 根据主键查询，联合主键按定义的顺序传入
+func Get[T any](ctx context.Context, sess Session, ids ...any) (*T, error) {
+	m, err := primaryKeyModel[T](sess)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) != len(m.PrimaryKeys) {
+		return nil, errs.NewPrimaryKeyCount(len(m.PrimaryKeys), len(ids))
+	}
+	ps := make([]Predicate, 0, len(ids))
+	for i, pk := range m.PrimaryKeys {
+		ps = append(ps, C(pk.GoName).Eq(ids[i]))
+	}
+	return NewSelector[T](sess).Where(ps...).Get(ctx)
+}
+
+// Save 主键全是零值时插入，否则按主键更新所有非主键字段。
+// 单列主键插入时不插入主键，交给数据库生成，整数主键会回填 LastInsertId
+func Save[T any](ctx context.Context, sess Session, entity *T) Result {
+	m, err := primaryKeyModel[T](sess)
+	if err != nil {
+		return Result{err: err}
+	}
+	pks, zero, err := primaryKeyValues(sess, m, entity)
+	if err != nil {
+		return Result{err: err}
+	}
+	if !zero {
+		cols := make([]Column, 0, len(m.Fields))
+		for _, fd := range m.Fields {
+			if !fd.PrimaryKey {
+				cols = append(cols, C(fd.GoName))
+			}
+		}
+		return NewUpdater[T](sess).Value(entity).Updates(cols...).Where(pks...).Exec(ctx)
+	}
+
+	if len(m.PrimaryKeys) > 1 {
+		return NewInserter[T](sess).Values(entity).Exec(ctx)
+	}
+	cols := make([]string, 0, len(m.Fields))
+	for _, fd := range m.Fields {
+		if !fd.PrimaryKey {
+			cols = append(cols, fd.GoName)
+		}
+	}
+	res := NewInserter[T](sess).Columns(cols...).Values(entity).Exec(ctx)
+	if res.err != nil {
+		return res
+	}
+	pkVal := reflect.ValueOf(entity).Elem().FieldByName(m.PrimaryKeys[0].GoName)
+	switch pkVal.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if id, err := res.LastInsertId(); err == nil {
+			pkVal.SetInt(id)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if id, err := res.LastInsertId(); err == nil {
+			pkVal.SetUint(uint64(id))
+		}
+	}
+	return res
+}
+
+// UpdateEntity 按主键更新所有非零值的非主键字段
+func UpdateEntity[T any](ctx context.Context, sess Session, entity *T) Result {
+	m, err := primaryKeyModel[T](sess)
+	if err != nil {
+		return Result{err: err}
+	}
+	pks, zero, err := primaryKeyValues(sess, m, entity)
+	if err != nil {
+		return Result{err: err}
+	}
+	if zero {
+		return Result{err: errs.ErrZeroPrimaryKey}
+	}
+	return NewUpdater[T](sess).Value(entity).OmitZero().Where(pks...).Exec(ctx)
+}
+
+// DeleteEntity 按主键删除
+func DeleteEntity[T any](ctx context.Context, sess Session, entity *T) Result {
+	m, err := primaryKeyModel[T](sess)
+	if err != nil {
+		return Result{err: err}
+	}
+	pks, zero, err := primaryKeyValues(sess, m, entity)
+	if err != nil {
+		return Result{err: err}
+	}
+	if zero {
+		return Result{err: errs.ErrZeroPrimaryKey}
+	}
+	return NewDeletor[T](sess).Where(pks...).Exec(ctx)
+}
+
+func primaryKeyModel[T any](sess Session) (*model.Model, error) {
+	m, err := sess.getCore().r.Get(new(T))
+	if err != nil {
+		return nil, err
+	}
+	if len(m.PrimaryKeys) == 0 {
+		return nil, errs.ErrNoPrimaryKey
+	}
+	return m, nil
+}
+
+// 返回主键条件，以及主键是否全是零值
+func primaryKeyValues(sess Session, m *model.Model, entity any) ([]Predicate, bool, error) {
+	val := sess.getCore().creator(m, entity)
+	ps := make([]Predicate, 0, len(m.PrimaryKeys))
+	zero := true
+	for _, pk := range m.PrimaryKeys {
+		v, err := val.Field(pk.GoName)
+		if err != nil {
+			return nil, false, err
+		}
+		if !isZero(v) {
+			zero = false
+		}
+		ps = append(ps, C(pk.GoName).Eq(v))
+	}
+	return ps, zero, nil
+}
+
+func isZero(v any) bool {
+	val := reflect.ValueOf(v)
+	return !val.IsValid() || val.IsZero()
+}
@@ -0,0 +1,120 @@
+package orm
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"gitee.com/youkelike/orm/internal/errs"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type KeyModel struct {
+	Id   int64 `orm:"pk=true"`
+	Name string
+	Age  int8
+}
+
+type CompositeKeyModel struct {
+	UserId  int64 `orm:"pk=true"`
+	OrderId int64 `orm:"pk=true"`
+	Amount  int
+}
+
+func TestGet(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+
+	rows := sqlmock.NewRows([]string{"id", "name", "age"})
+	rows.AddRow(1, "Tom", 18)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM key_model WHERE id=?;")).WithArgs(1).WillReturnRows(rows)
+	res, err := Get[KeyModel](context.Background(), db, 1)
+	require.NoError(t, err)
+	assert.Equal(t, &KeyModel{Id: 1, Name: "Tom", Age: 18}, res)
+
+	rows = sqlmock.NewRows([]string{"user_id", "order_id", "amount"})
+	rows.AddRow(1, 2, 100)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM composite_key_model WHERE (user_id=?) AND (order_id=?);")).
+		WithArgs(1, 2).WillReturnRows(rows)
+	cres, err := Get[CompositeKeyModel](context.Background(), db, 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, &CompositeKeyModel{UserId: 1, OrderId: 2, Amount: 100}, cres)
+
+	_, err = Get[CompositeKeyModel](context.Background(), db, 1)
+	assert.Equal(t, errs.NewPrimaryKeyCount(2, 1), err)
+
+	_, err = Get[TestModel](context.Background(), db, 1)
+	assert.Equal(t, errs.ErrNoPrimaryKey, err)
+}
+
+func TestSave(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+
+	// 主键是零值时插入，并回填自增主键
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO key_model (name,age) VALUES (?,?);")).
+		WithArgs("Tom", int8(18)).WillReturnResult(sqlmock.NewResult(10, 1))
+	pm := &KeyModel{Name: "Tom", Age: 18}
+	res := Save(context.Background(), db, pm)
+	require.NoError(t, res.Err())
+	assert.Equal(t, int64(10), pm.Id)
+
+	// 主键不是零值时更新
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE key_model SET name=?,age=? WHERE id=?;")).
+		WithArgs("Bob", int8(0), int64(10)).WillReturnResult(sqlmock.NewResult(0, 1))
+	pm.Name = "Bob"
+	pm.Age = 0
+	res = Save(context.Background(), db, pm)
+	require.NoError(t, res.Err())
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateEntity(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE key_model SET name=? WHERE id=?;")).
+		WithArgs("Tom", int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	res := UpdateEntity(context.Background(), db, &KeyModel{Id: 1, Name: "Tom"})
+	affected, err := res.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+
+	res = UpdateEntity(context.Background(), db, &KeyModel{Name: "Tom"})
+	assert.Equal(t, errs.ErrZeroPrimaryKey, res.Err())
+
+	res = UpdateEntity(context.Background(), db, &KeyModel{Id: 1})
+	assert.Equal(t, errs.ErrNoUpdateColumns, res.Err())
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteEntity(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM composite_key_model WHERE (user_id=?) AND (order_id=?);")).
+		WithArgs(int64(1), int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	err = db.DoTx(context.Background(), func(ctx context.Context, tx *Tx) error {
+		return DeleteEntity(ctx, tx, &CompositeKeyModel{UserId: 1, OrderId: 2}).Err()
+	}, nil)
+	require.NoError(t, err)
+
+	res := DeleteEntity(context.Background(), db, &KeyModel{})
+	assert.Equal(t, errs.ErrZeroPrimaryKey, res.Err())
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -20,6 +20,12 @@ func (r RawExpr) selectable() {}
 
 func (r RawExpr) expr() {}
 
+func (r RawExpr) orderBy() OrderBy {
+	return OrderBy{
+		expr: r,
+	}
+}
+
 func (r RawExpr) AsPredicate() Predicate {
 	return Predicate{
 		left: r,
@@ -0,0 +1,158 @@
+package orm
+
+import (
+	"reflect"
+	"strings"
+
+	"gitee.com/youkelike/orm/internal/errs"
+	"gitee.com/youkelike/orm/model"
+)
+
+// Filter 把带标签的过滤结构体转换成条件，可以直接传给 Selector、Updater、Deletor 的 Where。
+// 标签的格式是 `orm:"col=Age,op=gte"`：
+//   - col 是模型的字段名，不写时用过滤结构体的字段名。Filter 不知道目标模型，生成 sql 时才会校验，
+//     需要立刻校验时用 FilterFor，不存在会返回 errs.NewUnknownField
+//   - op 可以是 eq、ne、gt、gte、lt、lte、like、in，不写时是 eq；in 的字段必须是切片或数组
+//
+// 零值字段和 nil 指针会被跳过，想按零值过滤时用指针；空切片也会被跳过；`orm:"-"` 的字段和未导出字段忽略；
+// 没有标签的匿名结构体字段会展开
+//
+//	type UserFilter struct {
+//		Name   string `orm:"op=like"`
+//		MinAge *int   `orm:"col=Age,op=gte"`
+//		IDs    []int  `orm:"col=Id,op=in"`
+//	}
+//	ps, err := Filter(UserFilter{Name: "Tom%", IDs: []int{1, 2}})
+//	NewSelector[User](db).Where(ps...)
+func Filter(obj any) ([]Predicate, error) {
+	return filter(nil, obj)
+}
+
+// FilterFor 和 Filter 一样，但是立刻按 T 的模型校验过滤结构体中的列
+//
+//	ps, err := FilterFor[User](db, UserFilter{Name: "Tom%"})
+func FilterFor[T any](sess Session, obj any) ([]Predicate, error) {
+	m, err := sess.getCore().r.Get(new(T))
+	if err != nil {
+		return nil, err
+	}
+	return filter(m, obj)
+}
+
+func filter(m *model.Model, obj any) ([]Predicate, error) {
+	val := reflect.ValueOf(obj)
+	if val.Kind() == reflect.Pointer {
+		if val.IsNil() {
+			return nil, nil
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return nil, errs.ErrPointerOnly
+	}
+	return filterPredicates(m, val, nil)
+}
+
+// m 不为 nil 时校验列是否属于模型
+func filterPredicates(m *model.Model, val reflect.Value, ps []Predicate) ([]Predicate, error) {
+	typ := val.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		fd := typ.Field(i)
+		if !fd.IsExported() {
+			continue
+		}
+		tag, tagged := fd.Tag.Lookup("orm")
+		if tag == "-" {
+			continue
+		}
+		fv := val.Field(i)
+		ft := fd.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if fd.Anonymous && !tagged && ft.Kind() == reflect.Struct {
+			if fv.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			var err error
+			if ps, err = filterPredicates(m, fv, ps); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// 先校验列，没有值的字段写错了列名也要报错
+		col, o, err := parseFilterTag(fd.Name, tag)
+		if err != nil {
+			return nil, err
+		}
+		if m != nil {
+			if _, ok := m.FieldMap[col]; !ok {
+				return nil, errs.NewUnknownField(col)
+			}
+		}
+		if fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		} else if fv.IsZero() {
+			continue
+		}
+		if o != opIn {
+			ps = append(ps, Predicate{left: C(col), op: o, right: valueOf(fv.Interface())})
+			continue
+		}
+		if fv.Kind() != reflect.Slice && fv.Kind() != reflect.Array {
+			return nil, errs.NewFilterInNotSlice(fd.Name)
+		}
+		if fv.Len() == 0 {
+			continue
+		}
+		args := make([]any, 0, fv.Len())
+		for j := 0; j < fv.Len(); j++ {
+			args = append(args, fv.Index(j).Interface())
+		}
+		ps = append(ps, C(col).In(args...))
+	}
+	return ps, nil
+}
+
+var filterOps = map[string]op{
+	"eq":   opEq,
+	"ne":   opNotEq,
+	"gt":   opGt,
+	"gte":  opGtEq,
+	"lt":   opLt,
+	"lte":  opLtEq,
+	"like": opLike,
+	"in":   opIn,
+}
+
+// 返回条件中的列名和操作符
+func parseFilterTag(name, tag string) (string, op, error) {
+	col, o := name, opEq
+	if tag == "" {
+		return col, o, nil
+	}
+	for _, pair := range strings.Split(tag, ",") {
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok || val == "" {
+			return "", "", errs.NewInvalidTagContent(pair)
+		}
+		switch key {
+		case "col":
+			col = val
+		case "op":
+			if o, ok = filterOps[val]; !ok {
+				return "", "", errs.NewUnsupportedFilterOp(val)
+			}
+		default:
+			return "", "", errs.NewInvalidTagContent(pair)
+		}
+	}
+	return col, o, nil
+}
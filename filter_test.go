@@ -0,0 +1,189 @@
+package orm
+
+import (
+	"testing"
+
+	"gitee.com/youkelike/orm/internal/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter(t *testing.T) {
+	db := memoryDB(t)
+	type Page struct {
+		Limit int
+	}
+	type UserFilter struct {
+		FirstName string  `orm:"op=like"`
+		MinAge    *int8   `orm:"col=Age,op=gte"`
+		MaxAge    *int8   `orm:"col=Age,op=lt"`
+		IDs       []int64 `orm:"col=Id,op=in"`
+		Page      `orm:"-"`
+		keyword   string
+	}
+	zero := int8(0)
+
+	testCases := []struct {
+		name      string
+		filter    any
+		builder   func(ps ...Predicate) QueryBuilder
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			name:   "all fields",
+			filter: &UserFilter{FirstName: "To%", MinAge: &zero, IDs: []int64{1, 2, 3}, Page: Page{Limit: 10}, keyword: "x"},
+			builder: func(ps ...Predicate) QueryBuilder {
+				return NewSelector[TestModel](db).Where(ps...)
+			},
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM test_model WHERE ((first_name LIKE ?) AND (age>=?)) AND (id IN (?,?,?));",
+				Args: []any{"To%", int8(0), int64(1), int64(2), int64(3)},
+			},
+		},
+		{
+			name:   "skip zero and empty",
+			filter: UserFilter{IDs: []int64{}},
+			builder: func(ps ...Predicate) QueryBuilder {
+				return NewSelector[TestModel](db).Where(ps...)
+			},
+			wantQuery: &Query{
+				SQL: "SELECT * FROM test_model;",
+			},
+		},
+		{
+			name: "default eq and embedded",
+			filter: struct {
+				TestModel
+				Ne string `orm:"col=LastName,op=ne"`
+			}{TestModel: TestModel{Id: 7, Age: 18}, Ne: "Jerry"},
+			builder: func(ps ...Predicate) QueryBuilder {
+				return NewUpdater[TestModel](db).ValueMap(map[string]any{"FirstName": "Tom"}).Where(ps...)
+			},
+			wantQuery: &Query{
+				SQL:  "UPDATE test_model SET first_name=? WHERE ((id=?) AND (age=?)) AND (last_name!=?);",
+				Args: []any{"Tom", int64(7), int8(18), "Jerry"},
+			},
+		},
+		{
+			name: "deletor",
+			filter: struct {
+				Ids [2]int64 `orm:"col=Id,op=in"`
+			}{Ids: [2]int64{1, 2}},
+			builder: func(ps ...Predicate) QueryBuilder {
+				return NewDeletor[TestModel](db).Where(ps...)
+			},
+			wantQuery: &Query{
+				SQL:  "DELETE FROM test_model WHERE id IN (?,?);",
+				Args: []any{int64(1), int64(2)},
+			},
+		},
+		{
+			name: "unknown column",
+			filter: struct {
+				Email string
+			}{Email: "a@b.c"},
+			builder: func(ps ...Predicate) QueryBuilder {
+				return NewSelector[TestModel](db).Where(ps...)
+			},
+			wantErr: errs.NewUnknownField("Email"),
+		},
+		{
+			name: "unsupported op",
+			filter: struct {
+				Age int8 `orm:"op=between"`
+			}{Age: 1},
+			wantErr: errs.NewUnsupportedFilterOp("between"),
+		},
+		{
+			name: "invalid tag",
+			filter: struct {
+				Age int8 `orm:"column=age"`
+			}{Age: 1},
+			wantErr: errs.NewInvalidTagContent("column=age"),
+		},
+		{
+			name: "in not slice",
+			filter: struct {
+				Age int8 `orm:"op=in"`
+			}{Age: 1},
+			wantErr: errs.NewFilterInNotSlice("Age"),
+		},
+		{
+			name:    "not struct",
+			filter:  map[string]any{"Age": 1},
+			wantErr: errs.ErrPointerOnly,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ps, err := Filter(tc.filter)
+			if err == nil {
+				_, err = tc.builder(ps...).Build()
+			}
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			q, err := tc.builder(ps...).Build()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantQuery, q)
+		})
+	}
+}
+
+func TestFilterFor(t *testing.T) {
+	db := memoryDB(t)
+	type Base struct {
+		Id int64
+	}
+	type UserFilter struct {
+		*Base
+		FirstName string `orm:"op=like"`
+		MinAge    int8   `orm:"col=Age,op=gte"`
+	}
+	type BadFilter struct {
+		Name string `orm:"col=XXX"`
+	}
+
+	testCases := []struct {
+		name    string
+		filter  any
+		wantPs  []Predicate
+		wantErr error
+	}{
+		{
+			name:   "valid",
+			filter: UserFilter{Base: &Base{Id: 1}, FirstName: "To%"},
+			wantPs: []Predicate{C("Id").Eq(int64(1)), C("FirstName").Like("To%")},
+		},
+		{
+			// 没有赋值的字段也要校验
+			name:    "unknown column without value",
+			filter:  BadFilter{},
+			wantErr: errs.NewUnknownField("XXX"),
+		},
+		{
+			name: "unknown column in embedded struct",
+			filter: struct {
+				BadFilter
+			}{},
+			wantErr: errs.NewUnknownField("XXX"),
+		},
+		{
+			name:    "not struct",
+			filter:  1,
+			wantErr: errs.ErrPointerOnly,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ps, err := FilterFor[TestModel](db, tc.filter)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantPs, ps)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package orm
+
+import "gitee.com/youkelike/orm/internal/errs"
+
+// MatchMode 是 mysql MATCH ... AGAINST 的检索模式
+type MatchMode string
+
+const (
+	MatchNaturalLanguage MatchMode = "IN NATURAL LANGUAGE MODE"
+	MatchBoolean         MatchMode = "IN BOOLEAN MODE"
+	// sqlite 的 FTS5 不支持
+	MatchQueryExpansion MatchMode = "WITH QUERY EXPANSION"
+)
+
+// MatchBuilder 是个中间对象，指定检索的列，再通过 Against 或者 Score 指定检索内容
+type MatchBuilder struct {
+	cols []Column
+}
+
+// Match 指定全文检索的列，mysql 上必须和某个 FULLTEXT 索引的列完全一致；
+// sqlite 上表必须是 FTS5 虚拟表，没有指定列时检索所有列
+//
+//	NewSelector[Article](db).Where(Match(C("Title"), C("Body")).Against("orm", MatchBoolean))
+func Match(cols ...Column) MatchBuilder {
+	return MatchBuilder{
+		cols: cols,
+	}
+}
+
+// Against 生成全文检索条件，mysql 是 MATCH (col,...) AGAINST (? mode)，sqlite 是 table MATCH ?。
+// sqlite 上自然语言模式会把 text 中的每个词转成 FTS5 字符串，布尔模式的 text 按 FTS5 查询表达式原样使用，
+// 括号或者引号不匹配时返回错误
+func (m MatchBuilder) Against(text string, mode MatchMode) Predicate {
+	return Predicate{
+		left: FullText{cols: m.cols, text: text, mode: mode},
+	}
+}
+
+// Score 生成相关度，可以放在 select 和 order by 中，值越大越相关。
+// sqlite 上用的是 -bm25(table)，要和 Against 条件一起使用，这时 text 和 mode 不起作用
+//
+//	score := Match(C("Title")).Score("orm", MatchNaturalLanguage)
+//	NewSelector[Article](db).Select(C("Id"), score.As("score")).Where(...).OrderBy(score.Desc())
+func (m MatchBuilder) Score(text string, mode MatchMode) FullText {
+	return FullText{cols: m.cols, text: text, mode: mode, score: true}
+}
+
+// FullText 是全文检索表达式，作为条件时只出现在 Predicate 的 left 中
+type FullText struct {
+	cols []Column
+	text string
+	mode MatchMode
+	// 为 true 时表示相关度，否则表示检索条件
+	score bool
+	alias string
+}
+
+func (f FullText) As(alias string) FullText {
+	f.alias = alias
+	return f
+}
+
+func (f FullText) selectable() {}
+
+func (f FullText) expr() {}
+
+func (f FullText) orderBy() OrderBy {
+	f.alias = ""
+	return OrderBy{
+		expr: f,
+	}
+}
+
+func (f FullText) Asc() OrderBy {
+	return Asc(f)
+}
+
+func (f FullText) Desc() OrderBy {
+	return Desc(f)
+}
+
+// FTS5 中代表整张表的隐藏列，列没有限定表时用模型的表
+func (b *builder) fullTextTable(cols []Column) (string, error) {
+	var table TableReference
+	for i, col := range cols {
+		if i > 0 && col.table != table {
+			return "", errs.ErrMatchMultiTable
+		}
+		table = col.table
+	}
+	switch t := table.(type) {
+	case nil:
+		return b.model.TableName, nil
+	case Table:
+		m, err := b.r.Get(t.entity)
+		if err != nil {
+			return "", err
+		}
+		if t.alias != "" {
+			return t.alias + "." + m.TableName, nil
+		}
+		return m.TableName, nil
+	default:
+		return "", errs.NewUnsupportTable(table)
+	}
+}
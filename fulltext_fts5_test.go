@@ -0,0 +1,53 @@
+//go:build sqlite_fts5
+
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// go-sqlite3 默认没有编译 FTS5，需要 go test -tags sqlite_fts5 运行
+func TestMatch_SQLiteFTS5(t *testing.T) {
+	type Article struct {
+		Title string
+		Body  string
+	}
+	type Scored struct {
+		Title string
+		Score float64
+	}
+	db, err := Open("sqlite3", "file:fts.db?cache=shared&mode=memory", DBWithDialect(DialectSQLite))
+	require.NoError(t, err)
+	ctx := context.Background()
+	_, err = db.db.ExecContext(ctx, "CREATE VIRTUAL TABLE article USING fts5(title, body)")
+	require.NoError(t, err)
+	res := NewInserter[Article](db).Values(
+		&Article{Title: "go orm", Body: "builder and dialect"},
+		&Article{Title: "java", Body: "go go go orm"},
+		&Article{Title: "rust", Body: "nothing here"},
+	).Exec(ctx)
+	require.NoError(t, res.Err())
+
+	arts, err := NewSelector[Article](db).Where(Match(C("Title")).Against("go", MatchNaturalLanguage)).GetMulti(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*Article{{Title: "go orm", Body: "builder and dialect"}}, arts)
+
+	// 自然语言模式下括号、花括号只是普通字符，不会破坏列过滤
+	arts, err = NewSelector[Article](db).Where(Match(C("Title")).Against("(go} {orm)", MatchNaturalLanguage)).GetMulti(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*Article{{Title: "go orm", Body: "builder and dialect"}}, arts)
+
+	a := TableOf(&Article{}).As("a")
+	scored, err := Project[Scored](NewSelector[Article](db).From(a).
+		Select(a.C("Title"), Match(a.C("Title")).Score("", "").As("Score")).
+		Where(Match(a.C("Title"), a.C("Body")).Against("go AND orm", MatchBoolean)).
+		OrderBy(C("Score").Desc())).GetMulti(ctx)
+	require.NoError(t, err)
+	require.Len(t, scored, 2)
+	assert.Equal(t, "java", scored[0].Title)
+	assert.Greater(t, scored[0].Score, scored[1].Score)
+}
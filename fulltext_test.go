@@ -0,0 +1,131 @@
+package orm
+
+import (
+	"testing"
+
+	"gitee.com/youkelike/orm/internal/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	mysqlDB := memoryDB(t)
+	sqliteDB := memoryDB(t, DBWithDialect(DialectSQLite))
+	postgresDB := memoryDB(t, DBWithDialect(DialectPostgreSQL))
+	type Article struct {
+		Id    int
+		Title string
+		Body  string
+	}
+	a := TableOf(&Article{}).As("a")
+
+	testCases := []struct {
+		name      string
+		s         QueryBuilder
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			name: "mysql natural language",
+			s:    NewSelector[Article](mysqlDB).Where(Match(C("Title"), C("Body")).Against("orm", MatchNaturalLanguage)),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM article WHERE MATCH (title,body) AGAINST (? IN NATURAL LANGUAGE MODE);",
+				Args: []any{"orm"},
+			},
+		},
+		{
+			name: "mysql boolean with score",
+			s: func() QueryBuilder {
+				score := Match(C("Title")).Score("+go -java", MatchBoolean)
+				return NewSelector[Article](mysqlDB).Select(C("Id"), score.As("score")).
+					Where(Match(C("Title")).Against("+go -java", MatchBoolean).And(C("Id").Gt(10))).
+					OrderBy(C("score").Desc(), score.Desc())
+			}(),
+			wantQuery: &Query{
+				SQL:  "SELECT id,MATCH (title) AGAINST (? IN BOOLEAN MODE) AS score FROM article WHERE (MATCH (title) AGAINST (? IN BOOLEAN MODE)) AND (id>?) ORDER BY score DESC,MATCH (title) AGAINST (? IN BOOLEAN MODE) DESC;",
+				Args: []any{"+go -java", "+go -java", 10, "+go -java"},
+			},
+		},
+		{
+			name: "mysql table alias",
+			s:    NewSelector[Article](mysqlDB).From(a).Where(Not(Match(a.C("Body")).Against("orm", ""))),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM article AS a WHERE  NOT (MATCH (a.body) AGAINST (?));",
+				Args: []any{"orm"},
+			},
+		},
+		{
+			name:    "mysql no columns",
+			s:       NewSelector[Article](mysqlDB).Where(Match().Against("orm", MatchBoolean)),
+			wantErr: errs.ErrMatchNoColumns,
+		},
+		{
+			name: "sqlite table",
+			s: NewSelector[Article](sqliteDB).Select(C("Title"), Match().Score("", "").As("score")).
+				Where(Match().Against("orm OR go", MatchBoolean)).OrderBy(C("score").Desc()),
+			wantQuery: &Query{
+				SQL:  "SELECT title,-bm25(article) AS score FROM article WHERE article MATCH ? ORDER BY score DESC;",
+				Args: []any{"orm OR go"},
+			},
+		},
+		{
+			name: "sqlite columns with alias",
+			s:    NewSelector[Article](sqliteDB).From(a).Where(Match(a.C("Title"), a.C("Body")).Against("orm", MatchNaturalLanguage)),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM article AS a WHERE a.article MATCH ?;",
+				Args: []any{`{title body} : ("orm")`},
+			},
+		},
+		{
+			name: "sqlite natural language quoted",
+			s:    NewSelector[Article](sqliteDB).Where(Match(C("Title")).Against(`go) OR {body} : (say "hi"`, MatchNaturalLanguage)),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM article WHERE article MATCH ?;",
+				Args: []any{`{title} : ("go)" "OR" "{body}" ":" "(say" """hi""")`},
+			},
+		},
+		{
+			name: "sqlite boolean parentheses in string",
+			s:    NewSelector[Article](sqliteDB).Where(Match(C("Title")).Against(`"go)}" AND (orm OR java)`, MatchBoolean)),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM article WHERE article MATCH ?;",
+				Args: []any{`{title} : ("go)}" AND (orm OR java))`},
+			},
+		},
+		{
+			name:    "sqlite boolean unbalanced parentheses",
+			s:       NewSelector[Article](sqliteDB).Where(Match(C("Title")).Against("go) OR {body} : (java", MatchBoolean)),
+			wantErr: errs.NewInvalidFullTextQuery("go) OR {body} : (java"),
+		},
+		{
+			name:    "sqlite boolean unclosed string",
+			s:       NewSelector[Article](sqliteDB).Where(Match().Against(`"go orm`, MatchBoolean)),
+			wantErr: errs.NewInvalidFullTextQuery(`"go orm`),
+		},
+		{
+			name:    "sqlite columns from different tables",
+			s:       NewSelector[Article](sqliteDB).From(a).Where(Match(C("Title"), a.C("Body")).Against("orm", MatchBoolean)),
+			wantErr: errs.ErrMatchMultiTable,
+		},
+		{
+			name:    "sqlite query expansion",
+			s:       NewSelector[Article](sqliteDB).Where(Match().Against("orm", MatchQueryExpansion)),
+			wantErr: errs.NewUnsupportedMatchMode("WITH QUERY EXPANSION"),
+		},
+		{
+			name:    "postgres",
+			s:       NewSelector[Article](postgresDB).Where(Match(C("Title")).Against("orm", MatchBoolean)),
+			wantErr: errs.ErrUnsupportedFullText,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := tc.s.Build()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, q)
+		})
+	}
+}
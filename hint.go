@@ -0,0 +1,15 @@
+package orm
+
+// 索引提示，目前只有 mysql 支持，生成 USE INDEX (a,b) 这样的子句
+type indexHint struct {
+	// USE、FORCE 或 IGNORE
+	typ     string
+	indexes []string
+}
+
+// 生成 SELECT、UPDATE、DELETE 关键字后面的 /*+ ... */，不支持的方言直接忽略
+func (b *builder) buildOptimizerHint(dialect Dialect, hint string) {
+	if hint != "" {
+		dialect.buildOptimizerHint(b, hint)
+	}
+}
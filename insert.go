@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"reflect"
+	"strings"
 
 	"gitee.com/youkelike/orm/internal/errs"
 	"gitee.com/youkelike/orm/model"
@@ -12,23 +13,59 @@ import (
 type UpsertBuilder[T any] struct {
 	i               *Inserter[T]
 	conflictColumns []string
+	conflictWhere   []Predicate
+	where           []Predicate
 }
 
 type Upsert struct {
 	assigns         []Assignable
 	conflictColumns []string
+	// 冲突目标上的条件，用于部分唯一索引：ON CONFLICT(col) WHERE ...
+	conflictWhere []Predicate
+	// 只有满足条件的冲突行才会被更新：DO UPDATE SET ... WHERE ...
+	where     []Predicate
+	doNothing bool
 }
 
 func (o *UpsertBuilder[T]) Update(assigns ...Assignable) *Inserter[T] {
-	o.i.upsert = &Upsert{assigns: assigns, conflictColumns: o.conflictColumns}
+	o.i.upsert = &Upsert{
+		assigns:         assigns,
+		conflictColumns: o.conflictColumns,
+		conflictWhere:   o.conflictWhere,
+		where:           o.where,
+	}
+	return o.i
+}
+
+// DoNothing 冲突时什么都不做，mysql 上生成 INSERT IGNORE，
+// 注意 INSERT IGNORE 还会把其他错误（例如数据被截断）降级成警告
+func (o *UpsertBuilder[T]) DoNothing() *Inserter[T] {
+	o.i.upsert = &Upsert{
+		conflictColumns: o.conflictColumns,
+		conflictWhere:   o.conflictWhere,
+		doNothing:       true,
+	}
 	return o.i
 }
 
+// ConflictColumns 指定冲突目标，mysql 按所有唯一索引判断冲突，会忽略这里的设置
 func (o *UpsertBuilder[T]) ConflictColumns(cols ...string) *UpsertBuilder[T] {
 	o.conflictColumns = cols
 	return o
 }
 
+// ConflictWhere 指定冲突目标的条件，用来匹配部分唯一索引，必须和 ConflictColumns 一起用，mysql 不支持
+func (o *UpsertBuilder[T]) ConflictWhere(ps ...Predicate) *UpsertBuilder[T] {
+	o.conflictWhere = ps
+	return o
+}
+
+// Where 指定冲突行要满足的更新条件，不满足的行保持不变，mysql 不支持
+func (o *UpsertBuilder[T]) Where(ps ...Predicate) *UpsertBuilder[T] {
+	o.where = ps
+	return o
+}
+
 type Assignable interface {
 	assign()
 }
@@ -38,7 +75,13 @@ type Inserter[T any] struct {
 	builder
 	columns []string
 	values  []*T
-	upsert  *Upsert
+	// 以字段名或列名为 key 的插入数据，设置了就不再使用 values
+	valueMaps []map[string]any
+	upsert    *Upsert
+	// 一条语句最多插入的行数
+	batchSize int
+	// INSERT ... SELECT 的数据来源
+	src QueryBuilder
 
 	sess Session
 }
@@ -47,8 +90,9 @@ func NewInserter[T any](sess Session) *Inserter[T] {
 	c := sess.getCore()
 	return &Inserter[T]{
 		builder: builder{
-			r:      c.r,
-			quoter: c.dialect.quoter(),
+			r:       c.r,
+			dialect: c.dialect,
+			quoter:  c.dialect.quoter(),
 		},
 		sess: sess,
 	}
@@ -75,15 +119,48 @@ func (i *Inserter[T]) Values(vals ...*T) *Inserter[T] {
 	return i
 }
 
+// ValueMaps 用 map 指定要插入的数据，key 可以是字段名也可以是列名。
+// 插入的列是所有 map 的 key 的并集，按模型中字段定义的顺序排列，某一行缺少的列写成 DEFAULT 使用列的默认值，
+// 要插入 NULL 时在 map 中显式写 nil；sqlite 不支持 DEFAULT，每一行都必须包含所有的列，否则返回错误；
+// 指定了 Columns 时只插入这些列，map 中出现了其他列会报错
+func (i *Inserter[T]) ValueMaps(ms ...map[string]any) *Inserter[T] {
+	i.valueMaps = ms
+	return i
+}
+
+// Select 把查询的结果插入到表中，生成 INSERT INTO t (a,b) SELECT ...，设置了就不再使用 Values 和 ValueMaps。
+// 查询的列要和 Columns 指定的列一一对应，没有指定 Columns 时对应模型的所有列。
+// sqlite 中和 Upsert 一起用时，查询必须带上 WHERE 条件，否则 ON CONFLICT 会被当成 join 的 ON 解析
+//
+//	NewInserter[UserHistory](db).Columns("Id", "Name").
+//		Select(NewSelector[User](db).Select(C("Id"), C("Name")).Where(C("Deleted").Eq(true)))
+func (i *Inserter[T]) Select(src QueryBuilder) *Inserter[T] {
+	i.src = src
+	return i
+}
+
+// BatchSize 指定一条 INSERT 语句最多插入多少行，行数更多时会拆成多条语句依次执行。
+// 不设置时按方言允许的占位符数量拆分，设置了也不会超过这个上限。
+// 拆分后在 DB 上执行会开启一个事务，在 Tx 上执行则直接用这个事务，
+// 返回的 Result 中 RowsAffected 是所有语句的和，LastInsertId 是最后一条语句的
+func (i *Inserter[T]) BatchSize(size int) *Inserter[T] {
+	i.batchSize = size
+	return i
+}
+
 func (i *Inserter[T]) Build() (*Query, error) {
-	if len(i.values) == 0 {
+	if i.memo != nil {
+		return i.memo, nil
+	}
+	i.reset()
+	if len(i.values) == 0 && len(i.valueMaps) == 0 && i.src == nil {
 		return nil, errs.ErrInsertZeroRow
 	}
 
-	i.sb.WriteString("INSERT INTO ")
+	i.sess.getCore().dialect.buildInsertPrefix(&i.builder, i.upsert)
 	if i.model == nil {
 		var err error
-		i.model, err = i.r.Get(i.values[0])
+		i.model, err = i.r.Get(new(T))
 		if err != nil {
 			return nil, err
 		}
@@ -93,16 +170,9 @@ func (i *Inserter[T]) Build() (*Query, error) {
 	// i.quote(m.TableName)
 	i.sb.WriteString(" (")
 
-	fields := i.model.Fields
-	if len(i.columns) > 0 {
-		fields = make([]*model.Field, 0, len(i.columns))
-		for _, fd := range i.columns {
-			fdMeta, ok := i.model.FieldMap[fd]
-			if !ok {
-				return nil, errs.NewUnknownField(fd)
-			}
-			fields = append(fields, fdMeta)
-		}
+	fields, rows, err := i.insertFields()
+	if err != nil {
+		return nil, err
 	}
 
 	for idx, field := range fields {
@@ -113,9 +183,37 @@ func (i *Inserter[T]) Build() (*Query, error) {
 		// i.quote(field.ColName)
 	}
 	i.sb.WriteString(")")
+	if i.src != nil {
+		q, err := i.src.Build()
+		if err != nil {
+			return nil, err
+		}
+		i.sb.WriteString(" ")
+		i.sb.WriteString(strings.TrimSuffix(q.SQL, ";"))
+		i.addArgs(q.Args...)
+	} else {
+		i.buildValues(fields, rows)
+	}
+
+	if i.upsert != nil {
+		err := i.sess.getCore().dialect.buildUpsert(&i.builder, i.upsert)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	i.sb.WriteString(";")
+	return i.done(), nil
+}
+
+func (i *Inserter[T]) buildValues(fields []*model.Field, rows []map[*model.Field]any) {
 	i.sb.WriteString(" VALUES ")
-	i.args = make([]any, 0, len(i.values)*len(fields))
-	for r := range i.values {
+	cnt := len(i.values)
+	if rows != nil {
+		cnt = len(rows)
+	}
+	i.args = make([]any, 0, cnt*len(fields))
+	for r := 0; r < cnt; r++ {
 		if r > 0 {
 			i.sb.WriteString(",")
 		}
@@ -124,25 +222,109 @@ func (i *Inserter[T]) Build() (*Query, error) {
 			if c > 0 {
 				i.sb.WriteString(",")
 			}
+			if rows != nil {
+				// map 中没有的列用默认值，不能用 NULL 覆盖掉列的默认值
+				val, ok := rows[r][field]
+				if !ok {
+					i.sb.WriteString("DEFAULT")
+					continue
+				}
+				i.sb.WriteString("?")
+				i.addArgs(val)
+				continue
+			}
 			i.sb.WriteString("?")
 			val := reflect.ValueOf(i.values[r]).Elem().FieldByName(field.GoName).Interface()
 			i.addArgs(val)
 		}
 		i.sb.WriteString(")")
 	}
+}
 
-	if i.upsert != nil {
-		err := i.sess.getCore().dialect.buildUpsert(&i.builder, i.upsert)
+// 确定要插入的列，使用 valueMaps 时同时返回转换后的行数据
+func (i *Inserter[T]) insertFields() ([]*model.Field, []map[*model.Field]any, error) {
+	fields := i.model.Fields
+	if len(i.columns) > 0 {
+		fields = make([]*model.Field, 0, len(i.columns))
+		for _, fd := range i.columns {
+			fdMeta, ok := i.model.FieldMap[fd]
+			if !ok {
+				return nil, nil, errs.NewUnknownField(fd)
+			}
+			fields = append(fields, fdMeta)
+		}
+	}
+	if len(i.valueMaps) == 0 || i.src != nil {
+		return fields, nil, nil
+	}
+	return i.mapRows(fields)
+}
+
+// 把 valueMaps 转换成以字段为 key 的行数据，并确定要插入的列
+func (i *Inserter[T]) mapRows(fields []*model.Field) ([]*model.Field, []map[*model.Field]any, error) {
+	rows := make([]map[*model.Field]any, 0, len(i.valueMaps))
+	used := make(map[*model.Field]bool, len(fields))
+	for _, m := range i.valueMaps {
+		row, err := i.fieldValues(m)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+		for fd := range row {
+			used[fd] = true
 		}
+		rows = append(rows, row)
 	}
 
-	i.sb.WriteString(";")
-	return &Query{
-		SQL:  i.sb.String(),
-		Args: i.args,
-	}, nil
+	if len(i.columns) > 0 {
+		allowed := make(map[*model.Field]bool, len(fields))
+		for _, fd := range fields {
+			allowed[fd] = true
+		}
+		// 按字段定义的顺序检查，保证报错是稳定的
+		for _, fd := range i.model.Fields {
+			if used[fd] && !allowed[fd] {
+				return nil, nil, errs.NewUnknownField(fd.GoName)
+			}
+		}
+		return fields, rows, i.checkMissing(fields, rows)
+	}
+
+	fields = make([]*model.Field, 0, len(used))
+	for _, fd := range i.model.Fields {
+		if used[fd] {
+			fields = append(fields, fd)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, nil, errs.ErrInsertZeroRow
+	}
+	return fields, rows, i.checkMissing(fields, rows)
+}
+
+// 不支持 DEFAULT 的方言没法表示缺少的列，每一行都必须包含所有要插入的列
+func (i *Inserter[T]) checkMissing(fields []*model.Field, rows []map[*model.Field]any) error {
+	if i.dialect.supportDefaultValue() {
+		return nil
+	}
+	for _, row := range rows {
+		for _, fd := range fields {
+			if _, ok := row[fd]; !ok {
+				return errs.NewValueMapMissingColumn(fd.GoName)
+			}
+		}
+	}
+	return nil
+}
+
+// Clone 复制出一个独立的 Inserter，之后对任意一方调用的方法都不会影响另一方。
+// Values、ValueMaps 传入的数据和 Select 的查询仍然是共用的
+func (i *Inserter[T]) Clone() *Inserter[T] {
+	c := *i
+	c.builder = i.builder.clone()
+	c.columns = append([]string(nil), i.columns...)
+	c.values = append([]*T(nil), i.values...)
+	c.valueMaps = append([]map[string]any(nil), i.valueMaps...)
+	return &c
 }
 
 func (i *Inserter[T]) Exec(ctx context.Context) Result {
@@ -154,6 +336,12 @@ func (i *Inserter[T]) Exec(ctx context.Context) Result {
 		}
 	}
 
+	// 出错时不拆分，交给 Build 报告错误
+	size, cnt, cols, err := i.chunkSize()
+	if err == nil && i.src == nil && cnt > size {
+		return i.execChunks(ctx, size, cnt, cols)
+	}
+
 	res := exec(ctx, &QueryContext{
 		Type:    "INSERT",
 		Builder: i,
@@ -172,6 +360,96 @@ func (i *Inserter[T]) Exec(ctx context.Context) Result {
 	}
 }
 
+// 返回一条语句最多插入的行数、总行数和要插入的列
+func (i *Inserter[T]) chunkSize() (int, int, []string, error) {
+	fields, rows, err := i.insertFields()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	cnt := len(i.values)
+	if rows != nil {
+		cnt = len(rows)
+	}
+
+	// upsert 的赋值、条件每条语句都会占用占位符，剩下的才能分给每一行
+	fixed, err := i.upsertArgs()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	limit := i.sess.getCore().dialect.maxArgs() - fixed
+	size := cnt
+	if len(fields) > 0 {
+		size = limit / len(fields)
+	}
+	if size < 1 {
+		size = 1
+	}
+	if i.batchSize > 0 && i.batchSize < size {
+		size = i.batchSize
+	}
+
+	cols := make([]string, 0, len(fields))
+	for _, fd := range fields {
+		cols = append(cols, fd.GoName)
+	}
+	return size, cnt, cols, nil
+}
+
+// 单独生成一次 upsert 子句，数出其中的参数个数
+func (i *Inserter[T]) upsertArgs() (int, error) {
+	if i.upsert == nil {
+		return 0, nil
+	}
+	b := i.builder.clone()
+	if err := i.dialect.buildUpsert(&b, i.upsert); err != nil {
+		return 0, err
+	}
+	return len(b.args), nil
+}
+
+// 分批执行，每一批都指定相同的列，保证和不拆分时插入的数据一致
+func (i *Inserter[T]) execChunks(ctx context.Context, size, cnt int, cols []string) Result {
+	var results multiResult
+	run := func(ctx context.Context, sess Session) error {
+		for start := 0; start < cnt; start += size {
+			end := start + size
+			if end > cnt {
+				end = cnt
+			}
+			chunk := NewInserter[T](sess).Columns(cols...)
+			chunk.upsert = i.upsert
+			if len(i.valueMaps) > 0 {
+				chunk.valueMaps = i.valueMaps[start:end]
+			} else {
+				chunk.values = i.values[start:end]
+			}
+			res := chunk.Exec(ctx)
+			if res.err != nil {
+				return res.err
+			}
+			results = append(results, res.res)
+		}
+		return nil
+	}
+
+	var err error
+	if db, ok := i.sess.(*DB); ok {
+		err = db.DoTx(ctx, func(ctx context.Context, tx *Tx) error {
+			return run(ctx, tx)
+		}, nil)
+	} else {
+		err = run(ctx, i.sess)
+	}
+	if err != nil {
+		return Result{
+			err: err,
+		}
+	}
+	return Result{
+		res: results,
+	}
+}
+
 // var _ Handler = (&Inserter[any]{}).execHandler
 
 // func (i *Inserter[T]) execHandler(ctx context.Context, qc *QueryContext) *QueryResult {
@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"errors"
+	"regexp"
+	"strings"
 	"testing"
 
 	"gitee.com/youkelike/orm/internal/errs"
@@ -29,6 +31,22 @@ func TestInserter_Build(t *testing.T) {
 		wantErr   error
 		wantQuery *Query
 	}{
+		{
+			// Clone 之后修改原来的 Inserter 不影响复制出来的
+			name: "clone",
+			i: func() QueryBuilder {
+				base := NewInserter[TestModel](db).Columns("Id", "Age").Values(&TestModel{Id: 1, Age: 18})
+				// 先 Build 过也能 Clone
+				_, _ = base.Build()
+				c := base.Clone().Upsert().Update(C("Age"))
+				base.Columns("Id").Values(&TestModel{Id: 2}).Upsert().Update(Assign("Age", 20))
+				return c
+			}(),
+			wantQuery: &Query{
+				SQL:  "INSERT INTO test_model (id,age) VALUES (?,?) ON DUPLICATE KEY UPDATE age=VALUES(age);",
+				Args: []any{int64(1), int8(18)},
+			},
+		},
 		{
 			name: "upsert",
 			i: NewInserter[TestModel](db).Values(&TestModel{
@@ -55,6 +73,64 @@ func TestInserter_Build(t *testing.T) {
 				Args: []any{int64(1), "Tom", int8(18), &sql.NullString{Valid: true, String: "Jerry"}},
 			},
 		},
+		{
+			name: "upsert do nothing",
+			i: NewInserter[TestModel](db).Values(&TestModel{
+				Id:        1,
+				FirstName: "Tom",
+				Age:       18,
+				LastName:  &sql.NullString{Valid: true, String: "Jerry"},
+			}).Upsert().DoNothing(),
+			wantQuery: &Query{
+				SQL:  "INSERT IGNORE INTO test_model (id,first_name,age,last_name) VALUES (?,?,?,?);",
+				Args: []any{int64(1), "Tom", int8(18), &sql.NullString{Valid: true, String: "Jerry"}},
+			},
+		},
+		{
+			name: "upsert conflict where",
+			i: NewInserter[TestModel](db).Values(&TestModel{
+				Id:        1,
+				FirstName: "Tom",
+				Age:       18,
+				LastName:  &sql.NullString{Valid: true, String: "Jerry"},
+			}).Upsert().ConflictColumns("Id").ConflictWhere(C("Age").Gt(0)).DoNothing(),
+			wantErr: errs.NewUnsupportedUpsertClause("冲突目标条件"),
+		},
+		{
+			name: "upsert update where",
+			i: NewInserter[TestModel](db).Values(&TestModel{
+				Id:        1,
+				FirstName: "Tom",
+				Age:       18,
+				LastName:  &sql.NullString{Valid: true, String: "Jerry"},
+			}).Upsert().Where(C("Age").Lt(10)).Update(C("Age")),
+			wantErr: errs.NewUnsupportedUpsertClause("更新条件"),
+		},
+		{
+			name: "insert select",
+			i: NewInserter[KeyModel](db).Columns("Id", "Name").
+				Select(NewSelector[TestModel](db).Select(C("Id"), C("FirstName")).Where(C("Age").Gt(18))),
+			wantQuery: &Query{
+				SQL:  "INSERT INTO key_model (id,name) SELECT id,first_name FROM test_model WHERE age>?;",
+				Args: []any{18},
+			},
+		},
+		{
+			name: "insert select upsert",
+			i: NewInserter[KeyModel](db).Columns("Id", "Name").
+				Select(NewSelector[TestModel](db).Select(C("Id"), C("FirstName")).Where(C("Age").Gt(18))).
+				Upsert().Update(Assign("Name", "Tom")),
+			wantQuery: &Query{
+				SQL:  "INSERT INTO key_model (id,name) SELECT id,first_name FROM test_model WHERE age>? ON DUPLICATE KEY UPDATE name=?;",
+				Args: []any{18, "Tom"},
+			},
+		},
+		{
+			name: "insert select invalid",
+			i: NewInserter[KeyModel](db).Columns("Id", "Name").
+				Select(NewSelector[TestModel](db).Select(C("Invalid"))),
+			wantErr: errs.NewUnknownField("Invalid"),
+		},
 		{
 			name:    "no row",
 			i:       NewInserter[TestModel](db).Values(),
@@ -118,6 +194,39 @@ func TestInserter_Build(t *testing.T) {
 				Args: []any{int64(1), "Tom", int64(2), "Tom2"},
 			},
 		},
+		{
+			name: "value maps",
+			i: NewInserter[TestModel](db).ValueMaps(
+				map[string]any{"first_name": "Tom", "Age": 18},
+				map[string]any{"FirstName": "Jerry", "Id": 2, "Age": nil},
+			),
+			wantQuery: &Query{
+				SQL:  "INSERT INTO test_model (id,first_name,age) VALUES (DEFAULT,?,?),(?,?,?);",
+				Args: []any{"Tom", 18, 2, "Jerry", nil},
+			},
+		},
+		{
+			name: "value maps with columns",
+			i: NewInserter[TestModel](db).Columns("FirstName", "Age").ValueMaps(
+				map[string]any{"FirstName": "Tom"},
+			),
+			wantQuery: &Query{
+				SQL:  "INSERT INTO test_model (first_name,age) VALUES (?,DEFAULT);",
+				Args: []any{"Tom"},
+			},
+		},
+		{
+			name: "value maps out of columns",
+			i: NewInserter[TestModel](db).Columns("FirstName").ValueMaps(
+				map[string]any{"FirstName": "Tom", "age": 18},
+			),
+			wantErr: errs.NewUnknownField("Age"),
+		},
+		{
+			name:    "value maps unknown key",
+			i:       NewInserter[TestModel](db).ValueMaps(map[string]any{"xxx": 1}),
+			wantErr: errs.NewUnknownField("xxx"),
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -153,6 +262,85 @@ func TestSQLite_Inserter_Build(t *testing.T) {
 				Args: []any{int64(1), "Tom", int8(18), &sql.NullString{Valid: true, String: "Jerry"}, 10, "Bob"},
 			},
 		},
+		{
+			name: "upsert do nothing",
+			i: NewInserter[TestModel](db).Values(&TestModel{
+				Id:        1,
+				FirstName: "Tom",
+				Age:       18,
+				LastName:  &sql.NullString{Valid: true, String: "Jerry"},
+			}).Upsert().DoNothing(),
+			wantQuery: &Query{
+				SQL:  "INSERT INTO test_model (id,first_name,age,last_name) VALUES (?,?,?,?) ON CONFLICT DO NOTHING;",
+				Args: []any{int64(1), "Tom", int8(18), &sql.NullString{Valid: true, String: "Jerry"}},
+			},
+		},
+		{
+			name: "upsert conflict where do nothing",
+			i: NewInserter[TestModel](db).Values(&TestModel{
+				Id:        1,
+				FirstName: "Tom",
+				Age:       18,
+				LastName:  &sql.NullString{Valid: true, String: "Jerry"},
+			}).Upsert().ConflictColumns("FirstName").ConflictWhere(C("Age").Gt(0)).DoNothing(),
+			wantQuery: &Query{
+				SQL:  "INSERT INTO test_model (id,first_name,age,last_name) VALUES (?,?,?,?) ON CONFLICT(first_name) WHERE age>? DO NOTHING;",
+				Args: []any{int64(1), "Tom", int8(18), &sql.NullString{Valid: true, String: "Jerry"}, 0},
+			},
+		},
+		{
+			name: "upsert update where",
+			i: NewInserter[TestModel](db).Values(&TestModel{
+				Id:        1,
+				FirstName: "Tom",
+				Age:       18,
+				LastName:  &sql.NullString{Valid: true, String: "Jerry"},
+			}).Upsert().ConflictColumns("Id").Where(C("Age").Lt(10)).Update(C("Age")),
+			wantQuery: &Query{
+				SQL:  "INSERT INTO test_model (id,first_name,age,last_name) VALUES (?,?,?,?) ON CONFLICT(id) DO UPDATE SET age=excluded.age WHERE age<?;",
+				Args: []any{int64(1), "Tom", int8(18), &sql.NullString{Valid: true, String: "Jerry"}, 10},
+			},
+		},
+		{
+			name: "upsert update without conflict columns",
+			i: NewInserter[TestModel](db).Values(&TestModel{
+				Id:        1,
+				FirstName: "Tom",
+				Age:       18,
+				LastName:  &sql.NullString{Valid: true, String: "Jerry"},
+			}).Upsert().Update(C("Age")),
+			wantErr: errs.ErrNoConflictColumns,
+		},
+		{
+			name: "upsert conflict where without conflict columns",
+			i: NewInserter[TestModel](db).Values(&TestModel{
+				Id:        1,
+				FirstName: "Tom",
+				Age:       18,
+				LastName:  &sql.NullString{Valid: true, String: "Jerry"},
+			}).Upsert().ConflictWhere(C("Age").Gt(0)).DoNothing(),
+			wantErr: errs.ErrNoConflictColumns,
+		},
+		{
+			name: "value maps",
+			i: NewInserter[TestModel](db).ValueMaps(
+				map[string]any{"FirstName": "Tom", "Age": nil},
+				map[string]any{"FirstName": "Jerry", "Age": 18},
+			),
+			wantQuery: &Query{
+				SQL:  "INSERT INTO test_model (first_name,age) VALUES (?,?),(?,?);",
+				Args: []any{"Tom", nil, "Jerry", 18},
+			},
+		},
+		{
+			// sqlite 不能写 DEFAULT，也不能用 NULL 代替
+			name: "value maps missing column",
+			i: NewInserter[TestModel](db).ValueMaps(
+				map[string]any{"FirstName": "Tom"},
+				map[string]any{"FirstName": "Jerry", "Age": 18},
+			),
+			wantErr: errs.NewValueMapMissingColumn("Age"),
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -218,6 +406,117 @@ func TestInserter_Exec(t *testing.T) {
 	}
 }
 
+func TestInserter_ExecChunks(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+
+	rows := func(n int) []*TestModel {
+		res := make([]*TestModel, 0, n)
+		for i := 0; i < n; i++ {
+			res = append(res, &TestModel{Id: int64(i + 1)})
+		}
+		return res
+	}
+
+	t.Run("batch size", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO test_model (id,first_name,age,last_name) VALUES (?,?,?,?),(?,?,?,?);")).
+			WillReturnResult(sqlmock.NewResult(2, 2))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO test_model (id,first_name,age,last_name) VALUES (?,?,?,?);")).
+			WillReturnResult(sqlmock.NewResult(3, 1))
+		mock.ExpectCommit()
+
+		res := NewInserter[TestModel](db).Values(rows(3)...).BatchSize(2).Exec(context.Background())
+		affected, err := res.RowsAffected()
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), affected)
+		id, err := res.LastInsertId()
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), id)
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO .*").WillReturnResult(sqlmock.NewResult(2, 2))
+		mock.ExpectExec("INSERT INTO .*").WillReturnError(errors.New("db error"))
+		mock.ExpectRollback()
+
+		res := NewInserter[TestModel](db).Values(rows(3)...).BatchSize(2).Exec(context.Background())
+		assert.ErrorContains(t, res.Err(), "db error")
+	})
+
+	t.Run("in tx", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO .*").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO .*").WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+
+		tx, err := db.BeginTx(context.Background(), nil)
+		require.NoError(t, err)
+		res := NewInserter[TestModel](tx).Values(rows(2)...).BatchSize(1).Exec(context.Background())
+		affected, err := res.RowsAffected()
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), affected)
+		require.NoError(t, tx.Commit())
+	})
+
+	t.Run("value maps keep union columns", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO test_model (first_name,age) VALUES (?,DEFAULT);")).
+			WithArgs("Tom").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO test_model (first_name,age) VALUES (DEFAULT,?);")).
+			WithArgs(18).WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+
+		res := NewInserter[TestModel](db).ValueMaps(
+			map[string]any{"FirstName": "Tom"},
+			map[string]any{"Age": 18},
+		).BatchSize(1).Exec(context.Background())
+		require.NoError(t, res.Err())
+	})
+
+	t.Run("dialect limit", func(t *testing.T) {
+		sqliteDB, err := OpenDB(mockDB, DBWithDialect(DialectSQLite))
+		require.NoError(t, err)
+		// 4 列，999 个占位符一条语句最多 249 行
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO test_model (id,first_name,age,last_name) VALUES " +
+			strings.Repeat("(?,?,?,?),", 248) + "(?,?,?,?);")).WillReturnResult(sqlmock.NewResult(249, 249))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO test_model (id,first_name,age,last_name) VALUES (?,?,?,?);")).
+			WillReturnResult(sqlmock.NewResult(250, 1))
+		mock.ExpectCommit()
+
+		res := NewInserter[TestModel](sqliteDB).Values(rows(250)...).Exec(context.Background())
+		affected, err := res.RowsAffected()
+		require.NoError(t, err)
+		assert.Equal(t, int64(250), affected)
+	})
+
+	t.Run("dialect limit with upsert args", func(t *testing.T) {
+		sqliteDB, err := OpenDB(mockDB, DBWithDialect(DialectSQLite))
+		require.NoError(t, err)
+		// upsert 占用 4 个占位符，剩下 995 个，一条语句最多 248 行
+		upsert := " ON CONFLICT(id) WHERE age>? DO UPDATE SET age=?,first_name=? WHERE age<?;"
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO test_model (id,first_name,age,last_name) VALUES " +
+			strings.Repeat("(?,?,?,?),", 247) + "(?,?,?,?)" + upsert)).WillReturnResult(sqlmock.NewResult(248, 248))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO test_model (id,first_name,age,last_name) VALUES (?,?,?,?)" + upsert)).
+			WillReturnResult(sqlmock.NewResult(249, 1))
+		mock.ExpectCommit()
+
+		res := NewInserter[TestModel](sqliteDB).Values(rows(249)...).Upsert().
+			ConflictColumns("Id").ConflictWhere(C("Age").Gt(0)).Where(C("Age").Lt(10)).
+			Update(Assign("Age", 10), Assign("FirstName", "Tom")).Exec(context.Background())
+		affected, err := res.RowsAffected()
+		require.NoError(t, err)
+		assert.Equal(t, int64(249), affected)
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
 func memoryDB(t *testing.T, opts ...DBOption) *DB {
 	db, err := Open("sqlite3", "file:test.db?cache=shared&mode=memory", opts...)
 	require.NoError(t, err)
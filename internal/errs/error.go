@@ -11,7 +11,42 @@ var (
 	ErrInsertZeroRow    = errors.New("orm: 插入 0 行")
 	ErrNoGroupUseHaving = errors.New("orm: having 必须配合 group 使用")
 	ErrNoOrderByVerb    = errors.New("orm: order by 必须指定字段排序规则")
-	ErrScanEntityValid  = errors.New("orm: scan 的参数只支持结构体的指针")
+	ErrScanEntityValid  = errors.New("orm: join 结果只能映射到结构体")
+	ErrNoPrimaryKey     = errors.New("orm: 模型没有定义主键")
+	ErrInvalidBatchSize = errors.New("orm: 每批的数量必须大于 0")
+	// 联合主键没法用单列比较的方式翻页
+	ErrCompositeBatchKey = errors.New("orm: 分批查询只支持单列主键")
+	ErrBatchTxNeedDB     = errors.New("orm: 只有 DB 能为每一批开启独立的事务")
+	ErrBatchOrderLimit   = errors.New("orm: 分批查询自己决定排序和数量，不能指定 order by、offset、limit")
+	ErrInvalidPageSize   = errors.New("orm: 每页的数量必须大于 0")
+	ErrNoCursorKey       = errors.New("orm: 游标分页需要先通过 DBWithCursorKey 设置签名密钥")
+	ErrInvalidCursor     = errors.New("orm: 无效的分页游标")
+	// 排序不唯一时同一个游标可能对应多行，翻页会漏数据或者重复
+	ErrPaginateOrderNotUnique = errors.New("orm: 游标分页的 order by 必须包含全部主键")
+	// 游标里保存的是实体字段的值，只能按模型自己的列排序
+	ErrPaginateOrderNotColumn = errors.New("orm: 游标分页的 order by 只能使用模型的列")
+	ErrZeroPrimaryKey         = errors.New("orm: 主键是零值")
+	ErrNoUpdateColumns        = errors.New("orm: 没有需要更新的列")
+	ErrNoConflictColumns      = errors.New("orm: upsert 需要指定冲突列")
+	ErrMutationTargetNotFound = errors.New("orm: 多表更新、删除的表中找不到目标表")
+	ErrUnsupportedLock        = errors.New("orm: 当前方言不支持行锁")
+	ErrUnsupportedDistinctOn  = errors.New("orm: 当前方言不支持 DISTINCT ON")
+	// 自动提交模式下语句执行完锁就释放了，加锁没有意义
+	ErrLockOutsideTx = errors.New("orm: 加锁查询必须在事务中执行")
+	// 防止漏写条件导致整张表被更新或者删除
+	ErrNoWhere = errors.New("orm: UPDATE、DELETE 没有 WHERE 条件，确实要操作全表时请调用 AllowFullTable")
+	// LATERAL 的意义在于子查询可以引用左边的表，普通的表用不上
+	ErrLateralNotSubquery   = errors.New("orm: LATERAL 只能用在子查询上")
+	ErrUnsupportedIndexHint = errors.New("orm: 当前方言不支持索引提示")
+	// join 中每张表的索引提示各不相同，只支持单表查询
+	ErrIndexHintNotTable   = errors.New("orm: 索引提示只能用在单表查询上")
+	ErrUnsupportedFullText = errors.New("orm: 当前方言不支持全文检索")
+	// mysql 的 MATCH 必须列出 FULLTEXT 索引中的列
+	ErrMatchNoColumns = errors.New("orm: 全文检索必须指定列")
+	// 全文检索的列只能来自同一张表
+	ErrMatchMultiTable = errors.New("orm: 全文检索的列必须属于同一张表")
+	// On(And()) 这样的条件组合后是空的，不能生成 ON 子句
+	ErrEmptyJoinOn = errors.New("orm: join 的 ON 条件是空的")
 )
 
 func NewUnknownField(name string) error {
@@ -45,3 +80,65 @@ func NewUnsupportTable(table any) error {
 func NewUnknownUpdateValue() error {
 	return fmt.Errorf("orm: 缺少更新数据")
 }
+
+func NewJoinColumnWithoutTable(name string) error {
+	return fmt.Errorf("orm: join 结果映射时列 %s 必须指定表", name)
+}
+
+func NewScalarColumnCount(n int) error {
+	return fmt.Errorf("orm: 映射到标量时结果集只能有一列，实际有 %d 列", n)
+}
+
+func NewBatchKeyNotSelected(name string) error {
+	return fmt.Errorf("orm: 分批查询时查询列中必须包含主键 %s", name)
+}
+
+func NewPrimaryKeyCount(want, got int) error {
+	return fmt.Errorf("orm: 主键有 %d 列，传入了 %d 个值", want, got)
+}
+
+func NewDuplicateField(name string) error {
+	return fmt.Errorf("orm: 字段 %s 重复出现", name)
+}
+
+func NewUnsupportedUpsertClause(clause string) error {
+	return fmt.Errorf("orm: 当前方言的 upsert 不支持 %s", clause)
+}
+
+func NewUnsupportedMutation(clause string) error {
+	return fmt.Errorf("orm: 当前方言的 UPDATE、DELETE 不支持 %s", clause)
+}
+
+// NULL 和任何值比较的结果都是 NULL，游标条件会把后面的行全部过滤掉
+func NewPaginateOrderNullable(col string) error {
+	return fmt.Errorf("orm: 游标分页不能按可以为 NULL 的列 %s 排序", col)
+}
+
+// 游标的值是从查询到的实体中读取的，没查询的列读到的是零值
+func NewPaginateOrderNotSelected(col string) error {
+	return fmt.Errorf("orm: 游标分页的排序列 %s 必须在查询的列中", col)
+}
+
+func NewValueMapMissingColumn(name string) error {
+	return fmt.Errorf("orm: 当前方言不支持 DEFAULT，ValueMaps 的每一行都必须包含 %s", name)
+}
+
+func NewUnsupportedJoin(typ string) error {
+	return fmt.Errorf("orm: 当前方言不支持 %s", typ)
+}
+
+func NewUnsupportedMatchMode(mode string) error {
+	return fmt.Errorf("orm: 当前方言的全文检索不支持 %s", mode)
+}
+
+func NewInvalidFullTextQuery(text string) error {
+	return fmt.Errorf("orm: 全文检索表达式的括号或者引号不匹配 %s", text)
+}
+
+func NewUnsupportedFilterOp(op string) error {
+	return fmt.Errorf("orm: 不支持的过滤操作符 %s", op)
+}
+
+func NewFilterInNotSlice(name string) error {
+	return fmt.Errorf("orm: 过滤字段 %s 使用 in 时必须是切片或数组", name)
+}
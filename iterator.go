@@ -0,0 +1,62 @@
+package orm
+
+import (
+	"database/sql"
+
+	"gitee.com/youkelike/orm/internal/valuer"
+	"gitee.com/youkelike/orm/model"
+)
+
+// Iterator 逐行读取查询结果，不会一次性把所有数据加载到内存，适合导出、批处理这类大结果集的场景。
+// 用完之后必须调用 Close 释放连接：
+//
+//	it, err := NewSelector[TestModel](db).Iter(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	defer it.Close()
+//	for it.Next() {
+//		tm := it.Value()
+//	}
+//	return it.Err()
+type Iterator[T any] struct {
+	rows    *sql.Rows
+	model   *model.Model
+	creator valuer.Creator
+
+	cur *T
+	err error
+}
+
+// Next 读取下一行，没有数据或者出错时返回 false，出错的原因通过 Err 获取
+func (it *Iterator[T]) Next() bool {
+	if it.err != nil || !it.rows.Next() {
+		return false
+	}
+	tp := new(T)
+	if err := it.creator(it.model, tp).SetColumns(it.rows); err != nil {
+		it.err = err
+		it.cur = nil
+		return false
+	}
+	it.cur = tp
+	return true
+}
+
+// Value 返回当前行，每一行都是新创建的实例
+func (it *Iterator[T]) Value() *T {
+	return it.cur
+}
+
+// Err 返回迭代过程中的错误，包括映射结果出错和 rows.Err()
+func (it *Iterator[T]) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.rows.Err()
+}
+
+// Close 可以在读完之前调用，提前结束迭代
+func (it *Iterator[T]) Close() error {
+	return it.rows.Close()
+}
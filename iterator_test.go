@@ -0,0 +1,112 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelector_Iter(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+
+	t.Run("query error", func(t *testing.T) {
+		mock.ExpectQuery("SELECT .*").WillReturnError(errors.New("db error"))
+		it, err := NewSelector[TestModel](db).Iter(context.Background())
+		assert.Equal(t, errors.New("db error"), err)
+		assert.Nil(t, it)
+	})
+
+	t.Run("all rows", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "first_name", "age", "last_name"})
+		rows.AddRow("1", "Tom", "18", "Jerry")
+		rows.AddRow("2", "Bob", "20", nil)
+		mock.ExpectQuery("SELECT .*").WillReturnRows(rows).RowsWillBeClosed()
+
+		it, err := NewSelector[TestModel](db).Iter(context.Background())
+		require.NoError(t, err)
+		var res []*TestModel
+		for it.Next() {
+			res = append(res, it.Value())
+		}
+		require.NoError(t, it.Err())
+		require.NoError(t, it.Close())
+		assert.Equal(t, []*TestModel{
+			{Id: 1, FirstName: "Tom", Age: 18, LastName: &sql.NullString{Valid: true, String: "Jerry"}},
+			{Id: 2, FirstName: "Bob", Age: 20},
+		}, res)
+	})
+
+	t.Run("close early", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id"})
+		rows.AddRow("1")
+		rows.AddRow("2")
+		mock.ExpectQuery("SELECT .*").WillReturnRows(rows).RowsWillBeClosed()
+
+		it, err := NewSelector[TestModel](db).Iter(context.Background())
+		require.NoError(t, err)
+		require.True(t, it.Next())
+		assert.Equal(t, &TestModel{Id: 1}, it.Value())
+		require.NoError(t, it.Close())
+		assert.False(t, it.Next())
+	})
+
+	t.Run("rows error", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id"})
+		rows.AddRow("1")
+		rows.AddRow("2")
+		rows.RowError(1, errors.New("row error"))
+		mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+
+		it, err := NewSelector[TestModel](db).Iter(context.Background())
+		require.NoError(t, err)
+		defer it.Close()
+		cnt := 0
+		for it.Next() {
+			cnt++
+		}
+		assert.Equal(t, 1, cnt)
+		assert.Equal(t, errors.New("row error"), it.Err())
+	})
+
+	t.Run("scan error", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "age"})
+		rows.AddRow("1", "18")
+		rows.AddRow("2", "abc")
+		mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+
+		it, err := NewSelector[TestModel](db).Iter(context.Background())
+		require.NoError(t, err)
+		defer it.Close()
+		require.True(t, it.Next())
+		assert.False(t, it.Next())
+		assert.Error(t, it.Err())
+		assert.Nil(t, it.Value())
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSelector_GetMultiScanError(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+
+	// 出错的行不是最后一行时也要返回错误
+	rows := sqlmock.NewRows([]string{"id", "age"})
+	rows.AddRow("1", "abc")
+	rows.AddRow("2", "18")
+	mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+
+	res, err := NewSelector[TestModel](db).GetMulti(context.Background())
+	assert.Error(t, err)
+	assert.Nil(t, res)
+}
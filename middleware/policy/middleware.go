@@ -0,0 +1,138 @@
+package policy
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"gitee.com/youkelike/orm"
+)
+
+var (
+	ErrForbiddenStatement  = errors.New("policy: 禁止执行的语句")
+	ErrTooManyRowsAffected = errors.New("policy: 影响的行数超过上限")
+	ErrRowsLimitOutsideTx  = errors.New("policy: 限制了影响的行数，只能在事务中执行")
+)
+
+type allowKey struct{}
+
+// Allow 返回的 context 在这次调用链路上解除 Forbid、ForbidOutsideTx 对这些类型语句的限制，
+// 例如只在某个后台任务中允许执行 RAW
+func Allow(ctx context.Context, types ...string) context.Context {
+	allowed := make(map[string]bool, len(types))
+	if prev, ok := ctx.Value(allowKey{}).(map[string]bool); ok {
+		for typ := range prev {
+			allowed[typ] = true
+		}
+	}
+	for _, typ := range types {
+		allowed[typ] = true
+	}
+	return context.WithValue(ctx, allowKey{}, allowed)
+}
+
+func allowed(ctx context.Context, typ string) bool {
+	allowed, _ := ctx.Value(allowKey{}).(map[string]bool)
+	return allowed[typ]
+}
+
+// 这些类型的语句执行前就知道是写操作，执行结果里有影响的行数
+var mutations = map[string]bool{
+	"INSERT": true,
+	"UPDATE": true,
+	"DELETE": true,
+}
+
+// Rule 是自定义的检查规则，返回 error 时语句不会被执行。
+// 可以通过 qc.Sess 区分 DB 和 Tx，例如只允许在事务中执行 DELETE
+type Rule func(ctx context.Context, qc *orm.QueryContext) error
+
+// MiddlewareBuilder 在语句执行前后检查一些约束，用来兜底防止误操作
+type MiddlewareBuilder struct {
+	forbidden       map[string]bool
+	forbiddenOutTx  map[string]bool
+	maxRowsAffected int64
+	rules           []Rule
+}
+
+func NewMiddlewareBuilder() *MiddlewareBuilder {
+	return &MiddlewareBuilder{
+		forbidden:      make(map[string]bool),
+		forbiddenOutTx: make(map[string]bool),
+	}
+}
+
+// Forbid 禁止执行这些类型的语句，类型就是 QueryContext.Type，例如 "DELETE"、"RAW"。
+// 对用这个中间件的所有 DB、Tx 都生效，个别调用可以用 Allow 放开
+func (m *MiddlewareBuilder) Forbid(types ...string) *MiddlewareBuilder {
+	for _, typ := range types {
+		m.forbidden[typ] = true
+	}
+	return m
+}
+
+// ForbidOutsideTx 这些类型的语句只能在事务中执行，直接用 DB 执行时返回 ErrForbiddenStatement
+func (m *MiddlewareBuilder) ForbidOutsideTx(types ...string) *MiddlewareBuilder {
+	for _, typ := range types {
+		m.forbiddenOutTx[typ] = true
+	}
+	return m
+}
+
+// MaxRowsAffected 限制写操作影响的行数。
+// INSERT、UPDATE、DELETE 只能在事务中执行，不在事务中时直接返回 ErrRowsLimitOutsideTx，不会执行；
+// 执行后影响的行数超过 n 时回滚事务并返回 ErrTooManyRowsAffected，之后这个事务不能再用。
+// RAW 在执行前分不出读写，只检查在事务中执行的
+func (m *MiddlewareBuilder) MaxRowsAffected(n int64) *MiddlewareBuilder {
+	m.maxRowsAffected = n
+	return m
+}
+
+// Rule 添加自定义规则，按添加的顺序检查
+func (m *MiddlewareBuilder) Rule(rules ...Rule) *MiddlewareBuilder {
+	m.rules = append(m.rules, rules...)
+	return m
+}
+
+func (m MiddlewareBuilder) Build() orm.Middleware {
+	return func(next orm.Handler) orm.Handler {
+		return func(ctx context.Context, qc *orm.QueryContext) *orm.QueryResult {
+			tx, inTx := qc.Sess.(*orm.Tx)
+			if (m.forbidden[qc.Type] || m.forbiddenOutTx[qc.Type] && !inTx) && !allowed(ctx, qc.Type) {
+				return &orm.QueryResult{
+					Err: fmt.Errorf("%w: %s", ErrForbiddenStatement, qc.Type),
+				}
+			}
+			for _, rule := range m.rules {
+				if err := rule(ctx, qc); err != nil {
+					return &orm.QueryResult{
+						Err: err,
+					}
+				}
+			}
+			if m.maxRowsAffected > 0 && mutations[qc.Type] && !inTx {
+				return &orm.QueryResult{
+					Err: fmt.Errorf("%w: %s", ErrRowsLimitOutsideTx, qc.Type),
+				}
+			}
+
+			res := next(ctx, qc)
+			if res.Err != nil || m.maxRowsAffected <= 0 || !inTx {
+				return res
+			}
+			sqlRes, ok := res.Result.(sql.Result)
+			if !ok {
+				return res
+			}
+			affected, err := sqlRes.RowsAffected()
+			if err == nil && affected > m.maxRowsAffected {
+				res.Err = fmt.Errorf("%w: %d > %d", ErrTooManyRowsAffected, affected, m.maxRowsAffected)
+				if rbErr := tx.Rollback(); rbErr != nil {
+					res.Err = fmt.Errorf("%w，回滚失败：%s", res.Err, rbErr)
+				}
+			}
+			return res
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package policy
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"gitee.com/youkelike/orm"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddlewareBuilder(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	errNotInTx := errors.New("delete must run in tx")
+	m := NewMiddlewareBuilder().
+		Forbid("RAW").
+		MaxRowsAffected(1).
+		Rule(func(ctx context.Context, qc *orm.QueryContext) error {
+			if _, ok := qc.Sess.(*orm.Tx); !ok && qc.Type == "DELETE" {
+				return errNotInTx
+			}
+			return nil
+		})
+	db, err := orm.OpenDB(mockDB, orm.DBWithMiddlewares(m.Build()))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	t.Run("forbidden", func(t *testing.T) {
+		res := orm.RawQuery[TestModel](db, "DELETE FROM test_model").Exec(ctx)
+		assert.ErrorIs(t, res.Err(), ErrForbiddenStatement)
+	})
+
+	t.Run("allow", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM test_model").WillReturnResult(sqlmock.NewResult(0, 5))
+		res := orm.RawQuery[TestModel](db, "DELETE FROM test_model").Exec(Allow(ctx, "RAW"))
+		require.NoError(t, res.Err())
+	})
+
+	t.Run("rule", func(t *testing.T) {
+		res := orm.NewDeletor[TestModel](db).Where(orm.C("Id").Eq(1)).Exec(ctx)
+		assert.Equal(t, errNotInTx, res.Err())
+	})
+
+	t.Run("rows limit outside tx", func(t *testing.T) {
+		res := orm.NewUpdater[TestModel](db).Value(&TestModel{Age: 18}).Updates(orm.C("Age")).
+			Where(orm.C("Id").Eq(1)).Exec(ctx)
+		assert.ErrorIs(t, res.Err(), ErrRowsLimitOutsideTx)
+	})
+
+	t.Run("too many rows affected", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM test_model WHERE .*").WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectRollback()
+		var execErr error
+		err := db.DoTx(ctx, func(ctx context.Context, tx *orm.Tx) error {
+			if execErr = orm.NewDeletor[TestModel](tx).Where(orm.C("Age").Gt(18)).Exec(ctx).Err(); execErr != nil {
+				return execErr
+			}
+			return orm.NewDeletor[TestModel](tx).Where(orm.C("Age").Lt(10)).Exec(ctx).Err()
+		}, nil)
+		assert.ErrorIs(t, execErr, ErrTooManyRowsAffected)
+		assert.NotContains(t, execErr.Error(), "回滚失败")
+		assert.ErrorIs(t, err, ErrTooManyRowsAffected)
+	})
+
+	t.Run("pass", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE test_model SET .*").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		err := db.DoTx(ctx, func(ctx context.Context, tx *orm.Tx) error {
+			return orm.NewUpdater[TestModel](tx).Value(&TestModel{Age: 18}).Updates(orm.C("Age")).
+				Where(orm.C("Id").Eq(1)).Exec(ctx).Err()
+		}, nil)
+		require.NoError(t, err)
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+type TestModel struct {
+	Id        int64
+	FirstName string
+	Age       int8
+	LastName  *sql.NullString
+}
+
+func TestMiddlewareBuilder_ForbidOutsideTx(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	m := NewMiddlewareBuilder().ForbidOutsideTx("DELETE")
+	db, err := orm.OpenDB(mockDB, orm.DBWithMiddlewares(m.Build()))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	res := orm.NewDeletor[TestModel](db).Where(orm.C("Id").Eq(1)).Exec(ctx)
+	assert.ErrorIs(t, res.Err(), ErrForbiddenStatement)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM test_model WHERE .*").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	err = db.DoTx(ctx, func(ctx context.Context, tx *orm.Tx) error {
+		return orm.NewDeletor[TestModel](tx).Where(orm.C("Id").Eq(1)).Exec(ctx).Err()
+	}, nil)
+	require.NoError(t, err)
+
+	mock.ExpectExec("DELETE FROM test_model WHERE .*").WillReturnResult(sqlmock.NewResult(0, 1))
+	res = orm.NewDeletor[TestModel](db).Where(orm.C("Id").Eq(1)).Exec(Allow(ctx, "DELETE"))
+	require.NoError(t, res.Err())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
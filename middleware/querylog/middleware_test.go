@@ -3,12 +3,19 @@ package querylog
 import (
 	"context"
 	"database/sql"
+	"regexp"
 	"testing"
 
 	"gitee.com/youkelike/orm"
+	"gitee.com/youkelike/orm/middleware/opentelemetry"
+	"gitee.com/youkelike/orm/middleware/slowquery"
+	"github.com/DATA-DOG/go-sqlmock"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 func TestMiddlewareBuilder(t *testing.T) {
@@ -30,6 +37,85 @@ func TestMiddlewareBuilder(t *testing.T) {
 	assert.Equal(t, []any{int64(18), "", int8(0), (*sql.NullString)(nil)}, args)
 }
 
+// querylog、slowquery、opentelemetry 叠在一起时都会调用 Build，
+// 每个中间件记录的和最终发给数据库的都必须是同一条语句
+func TestMiddlewareBuilder_Stacked(t *testing.T) {
+	type record struct {
+		query string
+		args  []any
+	}
+	var logs, slows []record
+	var spans []string
+	ql := (&MiddlewareBuilder{}).LogFunc(func(q string, as []any) {
+		logs = append(logs, record{query: q, args: as})
+	})
+	sq := slowquery.NewMiddlerwareBuild(0).LogFunc(func(q string, as []any) {
+		slows = append(slows, record{query: q, args: as})
+	})
+	ot := opentelemetry.MiddlewareBuilder{Tracer: recordTracer{spans: &spans}}
+
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := orm.OpenDB(mockDB, orm.DBWithMiddlewares(ql.Build(), sq.Build(), ot.Build(), ql.Build()))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	selectSQL := "SELECT * FROM test_model WHERE id=?;"
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery(regexp.QuoteMeta(selectSQL)).WithArgs(10).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+	}
+	updateSQL := "UPDATE test_model SET age=? WHERE id=?;"
+	mock.ExpectExec(regexp.QuoteMeta(updateSQL)).WithArgs(int8(18), 10).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	insertSQL := "INSERT INTO test_model (id,first_name,age,last_name) VALUES (?,?,?,?);"
+	mock.ExpectExec(regexp.QuoteMeta(insertSQL)).WithArgs(int64(18), "", int8(0), nil).
+		WillReturnResult(sqlmock.NewResult(18, 1))
+
+	s := orm.NewSelector[TestModel](db).Where(orm.C("Id").Eq(10))
+	for i := 0; i < 2; i++ {
+		res, err := s.Get(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), res.Id)
+	}
+	err = orm.NewUpdater[TestModel](db).Value(&TestModel{Age: 18}).Updates(orm.C("Age")).
+		Where(orm.C("Id").Eq(10)).Exec(ctx).Err()
+	require.NoError(t, err)
+	err = orm.NewInserter[TestModel](db).Values(&TestModel{Id: 18}).Exec(ctx).Err()
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	selectRec := record{query: selectSQL, args: []any{10}}
+	updateRec := record{query: updateSQL, args: []any{int8(18), 10}}
+	insertRec := record{query: insertSQL, args: []any{int64(18), "", int8(0), (*sql.NullString)(nil)}}
+	assert.Equal(t, []record{selectRec, selectRec, selectRec, selectRec, updateRec, updateRec, insertRec, insertRec}, logs)
+	assert.Equal(t, []record{selectRec, selectRec, updateRec, insertRec}, slows)
+	assert.Equal(t, []string{selectSQL, selectSQL, updateSQL, insertSQL}, spans)
+}
+
+// 记录每个 span 上的 sql 属性
+type recordTracer struct {
+	noop.Tracer
+	spans *[]string
+}
+
+func (r recordTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return ctx, recordSpan{spans: r.spans}
+}
+
+type recordSpan struct {
+	noop.Span
+	spans *[]string
+}
+
+func (s recordSpan) SetAttributes(kv ...attribute.KeyValue) {
+	for _, attr := range kv {
+		if attr.Key == "sql" {
+			*s.spans = append(*s.spans, attr.Value.AsString())
+		}
+	}
+}
+
 type TestModel struct {
 	Id        int64
 	FirstName string
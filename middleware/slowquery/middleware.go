@@ -22,6 +22,11 @@ func NewMiddlerwareBuild(threshold time.Duration) *MiddlewareBuilder {
 	}
 }
 
+func (m *MiddlewareBuilder) LogFunc(fn func(query string, args []any)) *MiddlewareBuilder {
+	m.logFunc = fn
+	return m
+}
+
 func (m MiddlewareBuilder) Build() orm.Middleware {
 	return func(next orm.Handler) orm.Handler {
 		return func(ctx context.Context, qc *orm.QueryContext) *orm.QueryResult {
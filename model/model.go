@@ -10,7 +10,8 @@ import (
 )
 
 var (
-	tagColumn = "column"
+	tagColumn     = "column"
+	tagPrimaryKey = "pk"
 )
 
 type Registry interface {
@@ -29,6 +30,8 @@ type Model struct {
 	// 列名到字段的映射
 	ColumnMap map[string]*Field
 	Fields    []*Field
+	// 主键字段，按定义的顺序排列，多个字段时是联合主键
+	PrimaryKeys []*Field
 }
 
 type Field struct {
@@ -36,6 +39,8 @@ type Field struct {
 	ColName string
 	Typ     reflect.Type
 	Offset  uintptr
+	// 是否为主键
+	PrimaryKey bool
 }
 
 // 用接口的方式提供自定义表名的途径
@@ -64,6 +69,26 @@ func WithColumnName(Field, colname string) ModelOption {
 	}
 }
 
+// 指定主键，会覆盖标签中定义的主键，传多个字段就是联合主键
+func WithPrimaryKey(fields ...string) ModelOption {
+	return func(m *Model) error {
+		for _, fd := range m.PrimaryKeys {
+			fd.PrimaryKey = false
+		}
+		pks := make([]*Field, 0, len(fields))
+		for _, name := range fields {
+			fd, ok := m.FieldMap[name]
+			if !ok {
+				return errs.NewUnknownField(name)
+			}
+			fd.PrimaryKey = true
+			pks = append(pks, fd)
+		}
+		m.PrimaryKeys = pks
+		return nil
+	}
+}
+
 type registry struct {
 	models sync.Map
 }
@@ -104,6 +129,7 @@ func (r *registry) Get(val any) (*Model, error) {
 
 // 对于表名的解析顺序：结构体名、表名接口、表名 option
 // 对于表中列名的解析顺序：字段名、字段 tag、字段名 option
+// 对于主键的解析顺序：字段 tag、主键 option
 func (r *registry) Register(entity any, opts ...ModelOption) (*Model, error) {
 	typ := reflect.TypeOf(entity)
 	for typ.Kind() == reflect.Pointer {
@@ -118,6 +144,7 @@ func (r *registry) Register(entity any, opts ...ModelOption) (*Model, error) {
 	FieldMap := make(map[string]*Field, numField)
 	ColumnMap := make(map[string]*Field, numField)
 	Fields := make([]*Field, 0, numField)
+	var primaryKeys []*Field
 	for i := 0; i < numField; i++ {
 		fd := typ.Field(i)
 
@@ -132,10 +159,14 @@ func (r *registry) Register(entity any, opts ...ModelOption) (*Model, error) {
 		}
 
 		f := &Field{
-			GoName:  fd.Name,
-			ColName: ColName,
-			Typ:     fd.Type,
-			Offset:  fd.Offset,
+			GoName:     fd.Name,
+			ColName:    ColName,
+			Typ:        fd.Type,
+			Offset:     fd.Offset,
+			PrimaryKey: pair[tagPrimaryKey] == "true",
+		}
+		if f.PrimaryKey {
+			primaryKeys = append(primaryKeys, f)
 		}
 		FieldMap[fd.Name] = f
 		ColumnMap[ColName] = f
@@ -155,6 +186,8 @@ func (r *registry) Register(entity any, opts ...ModelOption) (*Model, error) {
 		FieldMap:  FieldMap,
 		ColumnMap: ColumnMap,
 		Fields:    Fields,
+
+		PrimaryKeys: primaryKeys,
 	}
 	for _, opt := range opts {
 		err := opt(m)
@@ -167,7 +200,7 @@ func (r *registry) Register(entity any, opts ...ModelOption) (*Model, error) {
 	return m, nil
 }
 
-// tag 是这个格式的：`orm:"column=id,xx=xx" xxx:"xx"`
+// tag 是这个格式的：`orm:"column=id,pk=true,xx=xx" xxx:"xx"`
 func (r *registry) parseTag(tag reflect.StructTag) (map[string]string, error) {
 	ormTag, ok := tag.Lookup("orm")
 	if !ok {
@@ -574,6 +574,81 @@ func TestModelWithColumnName(t *testing.T) {
 	}
 }
 
+func TestModelPrimaryKey(t *testing.T) {
+	type TagPK struct {
+		Id   int64 `orm:"pk=true"`
+		Name string
+	}
+	type CompositePK struct {
+		UserId  int64 `orm:"column=uid,pk=true"`
+		OrderId int64 `orm:"pk=true"`
+		Name    string
+	}
+	testCases := []struct {
+		name    string
+		entity  any
+		opts    []ModelOption
+		wantPKs []string
+		wantErr error
+	}{
+		{
+			name:   "no primary key",
+			entity: &TestModel{},
+		},
+		{
+			name:    "tag",
+			entity:  &TagPK{},
+			wantPKs: []string{"Id"},
+		},
+		{
+			name:    "composite tag",
+			entity:  &CompositePK{},
+			wantPKs: []string{"UserId", "OrderId"},
+		},
+		{
+			name:    "option",
+			entity:  &TestModel{},
+			opts:    []ModelOption{WithPrimaryKey("Id")},
+			wantPKs: []string{"Id"},
+		},
+		{
+			name:    "option override tag",
+			entity:  &CompositePK{},
+			opts:    []ModelOption{WithPrimaryKey("Name")},
+			wantPKs: []string{"Name"},
+		},
+		{
+			name:    "invalid option",
+			entity:  &TestModel{},
+			opts:    []ModelOption{WithPrimaryKey("XXX")},
+			wantErr: errs.NewUnknownField("XXX"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRegistry()
+			m, err := r.Register(tc.entity, tc.opts...)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			var pks []string
+			for _, fd := range m.PrimaryKeys {
+				assert.True(t, fd.PrimaryKey)
+				pks = append(pks, fd.GoName)
+			}
+			assert.Equal(t, tc.wantPKs, pks)
+			cnt := 0
+			for _, fd := range m.Fields {
+				if fd.PrimaryKey {
+					cnt++
+				}
+			}
+			assert.Equal(t, len(tc.wantPKs), cnt)
+		})
+	}
+}
+
 type TestModel struct {
 	Id        int64
 	FirstName string
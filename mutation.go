@@ -0,0 +1,84 @@
+package orm
+
+import (
+	"strconv"
+
+	"gitee.com/youkelike/orm/internal/errs"
+)
+
+// 这里是 Updater 和 Deletor 共用的多表、ORDER BY、LIMIT 相关的部分
+
+// 在 join 中找到模型对应的表，多表 UPDATE、DELETE 只会修改这张表
+func (b *builder) mutationTarget(table TableReference) (Table, error) {
+	switch t := table.(type) {
+	case Table:
+		m, err := b.r.Get(t.entity)
+		if err != nil {
+			return Table{}, err
+		}
+		if m == b.model {
+			return t, nil
+		}
+	case Join:
+		target, err := b.mutationTarget(t.left)
+		if err == nil || err != errs.ErrMutationTargetNotFound {
+			return target, err
+		}
+		return b.mutationTarget(t.right)
+	}
+	return Table{}, errs.ErrMutationTargetNotFound
+}
+
+// 把以目标表开头的 join 拆成目标表、剩下的表和目标表的连接条件，用于 UPDATE ... FROM 和 DELETE ... USING：
+// a JOIN b ON p1 JOIN c ON p2 => a, b JOIN c ON p2, p1
+func (b *builder) splitMutationJoin(table TableReference) (Table, TableReference, []Predicate, error) {
+	j, ok := table.(Join)
+	if !ok {
+		return Table{}, nil, nil, errs.ErrMutationTargetNotFound
+	}
+	left, ok := j.left.(Table)
+	if !ok {
+		target, rest, on, err := b.splitMutationJoin(j.left)
+		if err != nil {
+			return Table{}, nil, nil, err
+		}
+		j.left = rest
+		return target, j, on, nil
+	}
+
+	m, err := b.r.Get(left.entity)
+	if err != nil {
+		return Table{}, nil, nil, err
+	}
+	if m != b.model {
+		return Table{}, nil, nil, errs.ErrMutationTargetNotFound
+	}
+	// 连接条件要挪到 WHERE 中，只有内连接可以这么做
+	if j.typ != "JOIN" || j.lateral {
+		return Table{}, nil, nil, errs.NewUnsupportedMutation(j.typ)
+	}
+	if len(j.using) > 0 {
+		return Table{}, nil, nil, errs.NewUnsupportedMutation("JOIN USING")
+	}
+	if len(j.on) > 0 && And(j.on...).empty() {
+		return Table{}, nil, nil, errs.ErrEmptyJoinOn
+	}
+	return left, j.right, j.on, nil
+}
+
+func (b *builder) buildOrderLimit(dialect Dialect, joined bool, orderBy []OrderBy, limit int) error {
+	if len(orderBy) == 0 && limit == 0 {
+		return nil
+	}
+	if !dialect.mutation().orderLimit || joined {
+		return errs.NewUnsupportedMutation("ORDER BY 和 LIMIT")
+	}
+	if err := b.buildOrderBy(dialect, orderBy); err != nil {
+		return err
+	}
+	if limit > 0 {
+		b.sb.WriteString(" LIMIT ")
+		b.sb.WriteString(strconv.Itoa(limit))
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package orm
+
+// Orderable 可以放到 ORDER BY 中的对象：列、聚合函数、原生表达式，以及用它们构造的排序项，
+// 列还可以是 Select 中声明的别名
+//
+//	OrderBy(C("Age"), t2.C("Price").Desc().NullsLast(), Count(C("Id")).Desc(), C("cnt").Asc(), Desc(Raw("FIELD(id,3,1,2)")))
+type Orderable interface {
+	orderBy() OrderBy
+}
+
+// OrderBy 是 ORDER BY 中的一项，由表达式、排序规则以及 NULL 值的位置组成
+type OrderBy struct {
+	expr  Expression
+	order string
+	// FIRST 或 LAST，为空时由数据库决定
+	nulls string
+}
+
+// Asc(Sum(C("Age")))
+func Asc(expr Expression) OrderBy {
+	return OrderBy{
+		expr:  expr,
+		order: "ASC",
+	}
+}
+
+// Desc(Raw("FIELD(id,3,1,2)"))
+func Desc(expr Expression) OrderBy {
+	return OrderBy{
+		expr:  expr,
+		order: "DESC",
+	}
+}
+
+// NullsFirst 把 NULL 值排在最前面，mysql 上用 ISNULL(expr) DESC 模拟
+func (o OrderBy) NullsFirst() OrderBy {
+	o.nulls = "FIRST"
+	return o
+}
+
+// NullsLast 把 NULL 值排在最后面，mysql 上用 ISNULL(expr) 模拟
+func (o OrderBy) NullsLast() OrderBy {
+	o.nulls = "LAST"
+	return o
+}
+
+func (o OrderBy) orderBy() OrderBy {
+	return o
+}
+
+// 排序项是模型的列时返回这一列
+func (o OrderBy) column() (Column, bool) {
+	col, ok := o.expr.(Column)
+	return col, ok
+}
+
+// 反转排序规则，没有指定排序规则时原样返回，NULL 值的位置不变
+func (o OrderBy) reverse() OrderBy {
+	switch o.order {
+	case "ASC":
+		o.order = "DESC"
+	case "DESC":
+		o.order = "ASC"
+	}
+	return o
+}
+
+func orderBys(items []Orderable) []OrderBy {
+	res := make([]OrderBy, 0, len(items))
+	for _, item := range items {
+		res = append(res, item.orderBy())
+	}
+	return res
+}
@@ -0,0 +1,318 @@
+package orm
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitee.com/youkelike/orm/internal/errs"
+	"gitee.com/youkelike/orm/model"
+)
+
+// Page 是游标分页的一页数据，Next、Prev 为空表示没有下一页、上一页
+type Page[T any] struct {
+	Items []*T
+	Next  string
+	Prev  string
+}
+
+// Paginate 基于当前的 order by 做游标（keyset）分页，cursor 为空时取第一页，
+// 否则传入上一次返回的 Page.Next 或 Page.Prev。
+// order by 必须包含模型的全部主键才能保证排序唯一，支持 ASC、DESC 混用。
+// 游标用 DBWithCursorKey 设置的密钥签名，被篡改或者用在别的查询上都会返回 errs.ErrInvalidCursor
+func (s *Selector[T]) Paginate(ctx context.Context, cursor string, size int) (*Page[T], error) {
+	if size <= 0 {
+		return nil, errs.ErrInvalidPageSize
+	}
+	key := s.sess.getCore().cursorKey
+	if len(key) == 0 {
+		return nil, errs.ErrNoCursorKey
+	}
+	m, err := s.r.Get(new(T))
+	if err != nil {
+		return nil, err
+	}
+	if err = s.checkUniqueOrder(m); err != nil {
+		return nil, err
+	}
+
+	sign := s.cursorSign(m, key)
+	var cur pageCursor
+	if cursor != "" {
+		cur, err = decodeCursor(cursor, sign)
+		if err != nil {
+			return nil, err
+		}
+		if len(cur.Values) != len(s.orderBy) {
+			return nil, errs.ErrInvalidCursor
+		}
+	}
+
+	sub := s.derive(s.columns...)
+	// 例如用 FOR UPDATE SKIP LOCKED 分页取任务，锁要加在每一页的查询上
+	sub.lock, sub.lockWait = s.lock, s.lockWait
+	sub.limit = size + 1
+	sub.orderBy = make([]OrderBy, 0, len(s.orderBy))
+	for _, ob := range s.orderBy {
+		if cur.Prev {
+			ob = ob.reverse()
+		}
+		sub.orderBy = append(sub.orderBy, ob)
+	}
+	if cursor != "" {
+		where := make([]Predicate, 0, len(s.where)+1)
+		where = append(where, s.where...)
+		sub.where = append(where, keysetPredicate(sub.orderBy, cur.Values))
+	}
+
+	items, err := sub.GetMulti(ctx)
+	if err != nil && err != errs.ErrNoRows {
+		return nil, err
+	}
+	hasMore := len(items) > size
+	if hasMore {
+		items = items[:size]
+	}
+	if cur.Prev {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	page := &Page[T]{Items: items}
+	if len(items) == 0 {
+		return page, nil
+	}
+	// 往后翻时，还有数据才有下一页，从别的页翻过来的才有上一页；往前翻时反过来
+	if cur.Prev || hasMore {
+		page.Next, err = s.encodeCursor(m, items[len(items)-1], false, sign)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if (cur.Prev && hasMore) || (!cur.Prev && cursor != "") {
+		page.Prev, err = s.encodeCursor(m, items[0], true, sign)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// order by 中只能有 T 对应的表的列，必须指定排序规则，并且包含所有主键，
+// 指针和 sql.NullString 之类实现了 sql.Scanner 的列可能是 NULL，不能用来排序。
+// 游标的值从查询到的实体中读取，指定了查询列时排序的列也必须查询出来
+func (s *Selector[T]) checkUniqueOrder(m *model.Model) error {
+	if len(m.PrimaryKeys) == 0 {
+		return errs.ErrPaginateOrderNotUnique
+	}
+	ordered := make(map[string]bool, len(s.orderBy))
+	for _, ob := range s.orderBy {
+		col, ok := ob.column()
+		if !ok || !ownTable[T](col.table) {
+			return errs.ErrPaginateOrderNotColumn
+		}
+		if ob.order == "" {
+			return errs.ErrNoOrderByVerb
+		}
+		fd, ok := m.FieldMap[col.name]
+		if !ok {
+			return errs.NewUnknownField(col.name)
+		}
+		if nullable(fd.Typ) {
+			return errs.NewPaginateOrderNullable(col.name)
+		}
+		if !s.selected(col) {
+			return errs.NewPaginateOrderNotSelected(col.name)
+		}
+		ordered[col.name] = true
+	}
+	for _, pk := range m.PrimaryKeys {
+		if !ordered[pk.GoName] {
+			return errs.ErrPaginateOrderNotUnique
+		}
+	}
+	return nil
+}
+
+// 没有指定表，或者是 T 对应的表
+func ownTable[T any](table TableReference) bool {
+	if table == nil {
+		return true
+	}
+	t, ok := table.(Table)
+	return ok && reflect.TypeOf(t.entity) == reflect.TypeOf(new(T))
+}
+
+// 没有指定查询列时查询的是所有列
+func (s *Selector[T]) selected(col Column) bool {
+	if len(s.columns) == 0 {
+		return true
+	}
+	for _, sc := range s.columns {
+		if c, ok := sc.(Column); ok && c.name == col.name && c.alias == "" && ownTable[T](c.table) {
+			return true
+		}
+	}
+	return false
+}
+
+func nullable(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Pointer || reflect.PointerTo(typ).Implements(scannerType)
+}
+
+// 签名时带上表名和排序规则，游标不能用在别的查询上
+func (s *Selector[T]) cursorSign(m *model.Model, key []byte) func(payload []byte) []byte {
+	var sb strings.Builder
+	sb.WriteString(m.TableName)
+	for _, ob := range s.orderBy {
+		col, _ := ob.column()
+		sb.WriteString(",")
+		sb.WriteString(col.name)
+		sb.WriteString(" ")
+		sb.WriteString(ob.order)
+	}
+	scope := sb.String()
+	return func(payload []byte) []byte {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(scope))
+		mac.Write(payload)
+		return mac.Sum(nil)
+	}
+}
+
+func (s *Selector[T]) encodeCursor(m *model.Model, entity *T, prev bool, sign func([]byte) []byte) (string, error) {
+	val := s.sess.getCore().creator(m, entity)
+	cur := pageCursor{Prev: prev, Values: make([]cursorValue, 0, len(s.orderBy))}
+	for _, ob := range s.orderBy {
+		col, _ := ob.column()
+		fd, err := val.Field(col.name)
+		if err != nil {
+			return "", err
+		}
+		cv, err := newCursorValue(col.name, fd)
+		if err != nil {
+			return "", err
+		}
+		cur.Values = append(cur.Values, cv)
+	}
+	payload, err := json.Marshal(cur)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(sign(payload)), nil
+}
+
+func decodeCursor(cursor string, sign func([]byte) []byte) (pageCursor, error) {
+	var cur pageCursor
+	p, m, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return cur, errs.ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(p)
+	if err != nil {
+		return cur, errs.ErrInvalidCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(m)
+	if err != nil || !hmac.Equal(mac, sign(payload)) {
+		return cur, errs.ErrInvalidCursor
+	}
+	if err = json.Unmarshal(payload, &cur); err != nil {
+		return cur, errs.ErrInvalidCursor
+	}
+	return cur, nil
+}
+
+// 构造 (c1 > v1) OR (c1 = v1 AND ((c2 > v2) OR (c2 = v2 AND c3 > v3))) 形式的条件，
+// DESC 的列用 < 比较
+func keysetPredicate(orderBy []OrderBy, vals []cursorValue) Predicate {
+	var p Predicate
+	for i := len(orderBy) - 1; i >= 0; i-- {
+		col, _ := orderBy[i].column()
+		v := vals[i].value()
+		cmp := col.Gt(v)
+		if orderBy[i].order == "DESC" {
+			cmp = col.Lt(v)
+		}
+		if i == len(orderBy)-1 {
+			p = cmp
+			continue
+		}
+		p = cmp.Or(col.Eq(v).And(p))
+	}
+	return p
+}
+
+type pageCursor struct {
+	// 是否为往前翻的游标
+	Prev   bool          `json:"p,omitempty"`
+	Values []cursorValue `json:"v"`
+}
+
+// 排序列的值，带上类型以便原样还原成查询参数
+type cursorValue struct {
+	Type  string `json:"t"`
+	Value string `json:"v,omitempty"`
+}
+
+func newCursorValue(name string, val any) (cursorValue, error) {
+	// 先转换成驱动能接受的基本类型，指针、driver.Valuer 都会在这里被处理掉
+	dv, err := driver.DefaultParameterConverter.ConvertValue(val)
+	if err != nil {
+		return cursorValue{}, err
+	}
+	switch v := dv.(type) {
+	case nil:
+		// 自定义的 driver.Valuer 也可能返回 NULL
+		return cursorValue{}, errs.NewPaginateOrderNullable(name)
+	case int64:
+		return cursorValue{Type: "i", Value: strconv.FormatInt(v, 10)}, nil
+	case float64:
+		return cursorValue{Type: "f", Value: strconv.FormatFloat(v, 'g', -1, 64)}, nil
+	case bool:
+		return cursorValue{Type: "b", Value: strconv.FormatBool(v)}, nil
+	case string:
+		return cursorValue{Type: "s", Value: v}, nil
+	case []byte:
+		return cursorValue{Type: "y", Value: base64.RawURLEncoding.EncodeToString(v)}, nil
+	case time.Time:
+		return cursorValue{Type: "t", Value: v.Format(time.RFC3339Nano)}, nil
+	default:
+		return cursorValue{}, errs.NewUnsupportExpression(val)
+	}
+}
+
+// 签名校验通过的游标才会被还原，解析失败时原样返回字符串
+func (c cursorValue) value() any {
+	switch c.Type {
+	case "i":
+		if v, err := strconv.ParseInt(c.Value, 10, 64); err == nil {
+			return v
+		}
+	case "f":
+		if v, err := strconv.ParseFloat(c.Value, 64); err == nil {
+			return v
+		}
+	case "b":
+		if v, err := strconv.ParseBool(c.Value); err == nil {
+			return v
+		}
+	case "y":
+		if v, err := base64.RawURLEncoding.DecodeString(c.Value); err == nil {
+			return v
+		}
+	case "t":
+		if v, err := time.Parse(time.RFC3339Nano, c.Value); err == nil {
+			return v
+		}
+	}
+	return c.Value
+}
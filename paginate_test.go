@@ -0,0 +1,228 @@
+package orm
+
+import (
+	"context"
+	"database/sql/driver"
+	"regexp"
+	"testing"
+
+	"gitee.com/youkelike/orm/internal/errs"
+	"gitee.com/youkelike/orm/model"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelector_Paginate(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	r := model.NewRegistry()
+	_, err = r.Register(&TestModel{}, model.WithPrimaryKey("Id"))
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB, DBWithRegistry(r), DBWithCursorKey([]byte("secret")))
+	require.NoError(t, err)
+
+	newSelector := func() *Selector[TestModel] {
+		return NewSelector[TestModel](db).Where(C("FirstName").Eq("Tom")).OrderBy(C("Age").Desc(), C("Id").Asc())
+	}
+	ctx := context.Background()
+
+	// 第一页
+	rows := sqlmock.NewRows([]string{"id", "age"})
+	rows.AddRow(1, 20)
+	rows.AddRow(2, 18)
+	rows.AddRow(3, 18)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM test_model WHERE first_name=? ORDER BY age DESC,id ASC LIMIT 3;")).
+		WithArgs("Tom").WillReturnRows(rows)
+	s := newSelector()
+	page, err := s.Paginate(ctx, "", 2)
+	require.NoError(t, err)
+	// 不修改调用者的 Selector
+	assert.Nil(t, s.model)
+	assert.Equal(t, []*TestModel{{Id: 1, Age: 20}, {Id: 2, Age: 18}}, page.Items)
+	assert.NotEmpty(t, page.Next)
+	assert.Empty(t, page.Prev)
+
+	// 下一页，age 降序、id 升序
+	rows = sqlmock.NewRows([]string{"id", "age"})
+	rows.AddRow(3, 18)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM test_model WHERE (first_name=?) AND ((age<?) OR ((age=?) AND (id>?))) "+
+		"ORDER BY age DESC,id ASC LIMIT 3;")).
+		WithArgs("Tom", int64(18), int64(18), int64(2)).WillReturnRows(rows)
+	page, err = newSelector().Paginate(ctx, page.Next, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []*TestModel{{Id: 3, Age: 18}}, page.Items)
+	assert.Empty(t, page.Next)
+	assert.NotEmpty(t, page.Prev)
+
+	// 上一页，排序规则全部反过来，结果再倒序
+	rows = sqlmock.NewRows([]string{"id", "age"})
+	rows.AddRow(2, 18)
+	rows.AddRow(1, 20)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM test_model WHERE (first_name=?) AND ((age>?) OR ((age=?) AND (id<?))) "+
+		"ORDER BY age ASC,id DESC LIMIT 3;")).
+		WithArgs("Tom", int64(18), int64(18), int64(3)).WillReturnRows(rows)
+	page, err = newSelector().Paginate(ctx, page.Prev, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []*TestModel{{Id: 1, Age: 20}, {Id: 2, Age: 18}}, page.Items)
+	assert.NotEmpty(t, page.Next)
+	assert.Empty(t, page.Prev)
+	next := page.Next
+
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	testCases := []struct {
+		name    string
+		s       *Selector[TestModel]
+		cursor  string
+		size    int
+		wantErr error
+	}{
+		{
+			name:    "tampered cursor",
+			s:       newSelector(),
+			cursor:  "x" + next,
+			size:    2,
+			wantErr: errs.ErrInvalidCursor,
+		},
+		{
+			name:    "malformed cursor",
+			s:       newSelector(),
+			cursor:  "abc",
+			size:    2,
+			wantErr: errs.ErrInvalidCursor,
+		},
+		{
+			name:    "cursor of another query",
+			s:       NewSelector[TestModel](db).OrderBy(C("Age").Asc(), C("Id").Asc()),
+			cursor:  next,
+			size:    2,
+			wantErr: errs.ErrInvalidCursor,
+		},
+		{
+			name:    "order not unique",
+			s:       NewSelector[TestModel](db).OrderBy(C("Age").Desc()),
+			size:    2,
+			wantErr: errs.ErrPaginateOrderNotUnique,
+		},
+		{
+			name:    "order by aggregate",
+			s:       NewSelector[TestModel](db).OrderBy(Max(C("Age")).Desc(), C("Id").Asc()),
+			size:    2,
+			wantErr: errs.ErrPaginateOrderNotColumn,
+		},
+		{
+			name:    "order by column of another table",
+			s:       NewSelector[TestModel](db).OrderBy(TableOf(&KeyModel{}).As("t2").C("Id").Asc()),
+			size:    2,
+			wantErr: errs.ErrPaginateOrderNotColumn,
+		},
+		{
+			name:    "order column not selected",
+			s:       NewSelector[TestModel](db).Select(C("Id"), C("FirstName")).OrderBy(C("Age").Desc(), C("Id").Asc()),
+			size:    2,
+			wantErr: errs.NewPaginateOrderNotSelected("Age"),
+		},
+		{
+			name:    "order column aliased",
+			s:       NewSelector[TestModel](db).Select(C("Id"), C("Age").As("a")).OrderBy(C("Age").Desc(), C("Id").Asc()),
+			size:    2,
+			wantErr: errs.NewPaginateOrderNotSelected("Age"),
+		},
+		{
+			name:    "nullable order column",
+			s:       NewSelector[TestModel](db).OrderBy(C("LastName").Asc(), C("Id").Asc()),
+			size:    2,
+			wantErr: errs.NewPaginateOrderNullable("LastName"),
+		},
+		{
+			name:    "invalid size",
+			s:       newSelector(),
+			wantErr: errs.ErrInvalidPageSize,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.s.Paginate(ctx, tc.cursor, tc.size)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestSelector_PaginateLock(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	r := model.NewRegistry()
+	_, err = r.Register(&KeyModel{}, model.WithPrimaryKey("Id"))
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB, DBWithRegistry(r), DBWithCursorKey([]byte("secret")))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	rows := sqlmock.NewRows([]string{"id"})
+	rows.AddRow(1)
+	rows.AddRow(2)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM key_model ORDER BY id ASC LIMIT 2 FOR UPDATE SKIP LOCKED;")).
+		WillReturnRows(rows)
+	page, err := NewSelector[KeyModel](tx).ForUpdate().SkipLocked().OrderBy(C("Id").Asc()).Paginate(ctx, "", 1)
+	require.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+
+	rows = sqlmock.NewRows([]string{"id"})
+	rows.AddRow(2)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM key_model WHERE id>? ORDER BY id ASC LIMIT 2 FOR UPDATE SKIP LOCKED;")).
+		WithArgs(int64(1)).WillReturnRows(rows)
+	_, err = NewSelector[KeyModel](tx).ForUpdate().SkipLocked().OrderBy(C("Id").Asc()).Paginate(ctx, page.Next, 1)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// 不在事务中加锁要报错，不能悄悄去掉锁
+	_, err = NewSelector[KeyModel](db).ForUpdate().OrderBy(C("Id").Asc()).Paginate(ctx, "", 1)
+	assert.Equal(t, errs.ErrLockOutsideTx, err)
+}
+
+// 排序的值是 NULL 时没法生成下一页的条件，要返回错误而不是悄悄停在这一页
+func TestSelector_PaginateNullValue(t *testing.T) {
+	type ScoreModel struct {
+		Id    int64
+		Score nullableScore
+	}
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	r := model.NewRegistry()
+	_, err = r.Register(&ScoreModel{}, model.WithPrimaryKey("Id"))
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB, DBWithRegistry(r), DBWithCursorKey([]byte("secret")))
+	require.NoError(t, err)
+
+	rows := sqlmock.NewRows([]string{"id", "score"})
+	rows.AddRow(1, 5)
+	rows.AddRow(2, 0)
+	rows.AddRow(3, 7)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM score_model ORDER BY score ASC,id ASC LIMIT 3;")).WillReturnRows(rows)
+	_, err = NewSelector[ScoreModel](db).OrderBy(C("Score").Asc(), C("Id").Asc()).Paginate(context.Background(), "", 2)
+	assert.Equal(t, errs.NewPaginateOrderNullable("Score"), err)
+}
+
+// 零值存成 NULL
+type nullableScore int
+
+func (s nullableScore) Value() (driver.Value, error) {
+	if s == 0 {
+		return nil, nil
+	}
+	return int64(s), nil
+}
+
+func TestSelector_PaginateNoCursorKey(t *testing.T) {
+	mockDB, _, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+
+	_, err = NewSelector[TestModel](db).OrderBy(C("Id").Asc()).Paginate(context.Background(), "", 10)
+	assert.Equal(t, errs.ErrNoCursorKey, err)
+}
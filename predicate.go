@@ -3,9 +3,15 @@ package orm
 type op string
 
 const (
-	opEq op = "="
-	opGt op = ">"
-	opLt op = "<"
+	opEq    op = "="
+	opNotEq op = "!="
+	opGt    op = ">"
+	opGtEq  op = ">="
+	opLt    op = "<"
+	opLtEq  op = "<="
+
+	opLike op = "LIKE"
+	opIn   op = "IN"
 
 	opNot op = "NOT"
 	opAnd op = "AND"
@@ -16,6 +22,15 @@ func (o op) String() string {
 	return string(o)
 }
 
+// 关键字形式的操作符两边要加空格
+func (o op) keyword() bool {
+	switch o {
+	case opLike, opIn, opNot, opAnd, opOr:
+		return true
+	}
+	return false
+}
+
 type Predicate struct {
 	left  Expression
 	op    op
@@ -24,14 +39,41 @@ type Predicate struct {
 
 // Not(C("name").Eq("Tom"))
 func Not(p Predicate) Predicate {
+	if p.empty() {
+		return p
+	}
 	return Predicate{
 		op:    opNot,
 		right: p,
 	}
 }
 
-// C("id").Eq(12).And(C("name").Eq("Tom"))
+// And(C("id").Eq(12), C("name").Eq("Tom"))，没有条件时返回空条件
+func And(ps ...Predicate) Predicate {
+	var res Predicate
+	for _, p := range ps {
+		res = res.And(p)
+	}
+	return res
+}
+
+// Or(C("id").Eq(12), C("name").Eq("Tom"))，没有条件时返回空条件
+func Or(ps ...Predicate) Predicate {
+	var res Predicate
+	for _, p := range ps {
+		res = res.Or(p)
+	}
+	return res
+}
+
+// C("id").Eq(12).And(C("name").Eq("Tom"))，空条件不参与组合
 func (left Predicate) And(right Predicate) Predicate {
+	if left.empty() {
+		return right
+	}
+	if right.empty() {
+		return left
+	}
 	return Predicate{
 		left:  left,
 		op:    opAnd,
@@ -39,8 +81,14 @@ func (left Predicate) And(right Predicate) Predicate {
 	}
 }
 
-// C("id").Eq(12).Or(C("name").Eq("Tom"))
+// C("id").Eq(12).Or(C("name").Eq("Tom"))，空条件不参与组合
 func (left Predicate) Or(right Predicate) Predicate {
+	if left.empty() {
+		return right
+	}
+	if right.empty() {
+		return left
+	}
 	return Predicate{
 		left:  left,
 		op:    opOr,
@@ -48,6 +96,25 @@ func (left Predicate) Or(right Predicate) Predicate {
 	}
 }
 
+// 在已有条件后面追加，不能直接 append 到原来的切片上，它可能和派生出来的查询共用底层数组
+func andWhere(where []Predicate, ps []Predicate) []Predicate {
+	return append(where[:len(where):len(where)], ps...)
+}
+
+// 已有条件整体和新条件用 OR 连接，新条件之间是 AND
+func orWhere(where []Predicate, ps []Predicate) []Predicate {
+	p := Or(And(where...), And(ps...))
+	if p.empty() {
+		return nil
+	}
+	return []Predicate{p}
+}
+
+// 空条件来自没有元素的 And()、Or()，生成 sql 时会被忽略
+func (p Predicate) empty() bool {
+	return p.left == nil && p.op == "" && p.right == nil
+}
+
 func (p Predicate) expr() {}
 
 type value struct {
@@ -64,3 +131,10 @@ func valueOf(arg any) Expression {
 }
 
 func (value) expr() {}
+
+// IN 后面的一组值
+type values struct {
+	vals []any
+}
+
+func (values) expr() {}
@@ -0,0 +1,211 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"time"
+
+	"gitee.com/youkelike/orm/internal/errs"
+	"gitee.com/youkelike/orm/model"
+)
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+	mapType     = reflect.TypeOf(map[string]any{})
+)
+
+// Projector 按模型 T 构造查询，但把结果映射到另一种类型 R，R 可以是：
+//   - 结构体或结构体指针，列按列名（标签）、字段名或别名匹配字段
+//   - 标量，例如 int64、string、sql.NullString，此时结果集只能有一列
+//   - map[string]any，key 是列名
+type Projector[R any, T any] struct {
+	s *Selector[T]
+}
+
+// Project[Result](NewSelector[TestModel](db).Select(C("Age"), Count(C("Id")).As("cnt")).GroupBy(C("Age")))
+func Project[R any, T any](s *Selector[T]) *Projector[R, T] {
+	return &Projector[R, T]{
+		s: s,
+	}
+}
+
+func (p *Projector[R, T]) Get(ctx context.Context) (R, error) {
+	var r R
+	res := p.query(ctx, false)
+	if res.Result != nil {
+		return res.Result.(R), res.Err
+	}
+	return r, res.Err
+}
+
+func (p *Projector[R, T]) GetMulti(ctx context.Context) ([]R, error) {
+	res := p.query(ctx, true)
+	if res.Result != nil {
+		return res.Result.([]R), res.Err
+	}
+	return nil, res.Err
+}
+
+func (p *Projector[R, T]) query(ctx context.Context, multi bool) *QueryResult {
+	if err := p.s.checkLock(); err != nil {
+		return &QueryResult{
+			Err: err,
+		}
+	}
+	var err error
+	p.s.model, err = p.s.r.Get(new(T))
+	if err != nil {
+		return &QueryResult{
+			Err: err,
+		}
+	}
+
+	return handle(ctx, &QueryContext{
+		Type:    "SELECT",
+		Builder: p.s,
+		Model:   p.s.model,
+		Sess:    p.s.sess,
+	}, projectHandler[R](multi))
+}
+
+func projectHandler[R any](multi bool) Handler {
+	return func(ctx context.Context, qc *QueryContext) *QueryResult {
+		q, err := qc.Builder.Build()
+		if err != nil {
+			return &QueryResult{
+				Err: err,
+			}
+		}
+
+		rows, err := qc.Sess.queryContext(ctx, q.SQL, q.Args...)
+		if err != nil {
+			return &QueryResult{
+				Err: err,
+			}
+		}
+		defer rows.Close()
+
+		cs, err := rows.Columns()
+		if err != nil {
+			return &QueryResult{
+				Err: err,
+			}
+		}
+		scan, err := newRowScanner[R](qc.Sess.getCore().r, cs)
+		if err != nil {
+			return &QueryResult{
+				Err: err,
+			}
+		}
+
+		var res []R
+		for rows.Next() {
+			r, err := scan(rows)
+			if err != nil {
+				return &QueryResult{
+					Err: err,
+				}
+			}
+			res = append(res, r)
+			if !multi {
+				break
+			}
+		}
+		if err = rows.Err(); err != nil {
+			return &QueryResult{
+				Err: err,
+			}
+		}
+
+		if res == nil {
+			return &QueryResult{
+				Err: errs.ErrNoRows,
+			}
+		}
+		if !multi {
+			return &QueryResult{
+				Result: res[0],
+			}
+		}
+		return &QueryResult{
+			Result: res,
+		}
+	}
+}
+
+// 根据 R 的类型和结果集的列，生成把一行数据映射成 R 的函数，
+// 列和字段的对应关系只在这里计算一次，扫描每一行时直接用
+func newRowScanner[R any](r model.Registry, cs []string) (func(rows *sql.Rows) (R, error), error) {
+	typ := reflect.TypeOf((*R)(nil)).Elem()
+	elem := typ
+	if elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+
+	switch {
+	case typ == mapType:
+		return func(rows *sql.Rows) (R, error) {
+			vals := make([]any, len(cs))
+			dests := make([]any, len(cs))
+			for i := range vals {
+				dests[i] = &vals[i]
+			}
+			var res R
+			if err := rows.Scan(dests...); err != nil {
+				return res, err
+			}
+			m := make(map[string]any, len(cs))
+			for i, c := range cs {
+				m[c] = vals[i]
+			}
+			return any(m).(R), nil
+		}, nil
+	case elem.Kind() == reflect.Struct && !isScalarStruct(elem):
+		m, err := r.Get(reflect.New(elem).Interface())
+		if err != nil {
+			return nil, err
+		}
+		fds := make([]*model.Field, 0, len(cs))
+		for _, c := range cs {
+			fd, ok := m.ColumnMap[c]
+			if !ok {
+				fd, ok = m.FieldMap[c]
+			}
+			if !ok {
+				return nil, errs.NewUnknownColumn(c)
+			}
+			fds = append(fds, fd)
+		}
+		return func(rows *sql.Rows) (R, error) {
+			val := reflect.New(elem)
+			dests := make([]any, 0, len(fds))
+			for _, fd := range fds {
+				dests = append(dests, val.Elem().FieldByName(fd.GoName).Addr().Interface())
+			}
+			var res R
+			if err := rows.Scan(dests...); err != nil {
+				return res, err
+			}
+			if typ.Kind() == reflect.Pointer {
+				return val.Interface().(R), nil
+			}
+			return val.Elem().Interface().(R), nil
+		}, nil
+	default:
+		if len(cs) != 1 {
+			return nil, errs.NewScalarColumnCount(len(cs))
+		}
+		return func(rows *sql.Rows) (R, error) {
+			var res R
+			err := rows.Scan(&res)
+			return res, err
+		}, nil
+	}
+}
+
+// 实现了 sql.Scanner 的结构体（如 sql.NullString）和 time.Time 都当作标量处理
+func isScalarStruct(typ reflect.Type) bool {
+	return typ == timeType || reflect.PointerTo(typ).Implements(scannerType)
+}
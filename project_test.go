@@ -0,0 +1,130 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+
+	"gitee.com/youkelike/orm/internal/errs"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjector_GetMulti(t *testing.T) {
+	type AgeCount struct {
+		Age   int8
+		Count int64 `orm:"column=cnt"`
+	}
+
+	type AgeAvg struct {
+		Age int8
+		Avg float64
+	}
+
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+
+	t.Run("struct by tag", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"age", "cnt"})
+		rows.AddRow(18, 2)
+		rows.AddRow(20, 1)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT age,COUNT(id) AS cnt FROM test_model GROUP BY age;")).WillReturnRows(rows)
+
+		res, err := Project[*AgeCount](NewSelector[TestModel](db).
+			Select(C("Age"), Count(C("Id")).As("cnt")).GroupBy(C("Age"))).GetMulti(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []*AgeCount{{Age: 18, Count: 2}, {Age: 20, Count: 1}}, res)
+	})
+
+	t.Run("struct by field name", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"age", "Avg"})
+		rows.AddRow(18, 1.5)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT age,AVG(id) AS Avg FROM test_model GROUP BY age;")).WillReturnRows(rows)
+
+		res, err := Project[AgeAvg](NewSelector[TestModel](db).
+			Select(C("Age"), Avg(C("Id")).As("Avg")).GroupBy(C("Age"))).GetMulti(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []AgeAvg{{Age: 18, Avg: 1.5}}, res)
+	})
+
+	t.Run("struct unknown column", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"age", "total"})
+		rows.AddRow(18, 2)
+		mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+
+		_, err := Project[*AgeCount](NewSelector[TestModel](db).
+			Select(C("Age"), Count(C("Id")).As("total")).GroupBy(C("Age"))).GetMulti(context.Background())
+		assert.Equal(t, errs.NewUnknownColumn("total"), err)
+	})
+
+	t.Run("scalar", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id"})
+		rows.AddRow(1)
+		rows.AddRow(2)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM test_model;")).WillReturnRows(rows)
+
+		res, err := Project[int64](NewSelector[TestModel](db).Select(C("Id"))).GetMulti(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []int64{1, 2}, res)
+	})
+
+	t.Run("scanner scalar", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"last_name"})
+		rows.AddRow("Jerry")
+		rows.AddRow(nil)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT last_name FROM test_model;")).WillReturnRows(rows)
+
+		res, err := Project[sql.NullString](NewSelector[TestModel](db).Select(C("LastName"))).GetMulti(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []sql.NullString{{String: "Jerry", Valid: true}, {}}, res)
+	})
+
+	t.Run("scalar too many columns", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "age"})
+		rows.AddRow(1, 18)
+		mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+
+		_, err := Project[int64](NewSelector[TestModel](db).Select(C("Id"), C("Age"))).GetMulti(context.Background())
+		assert.Equal(t, errs.NewScalarColumnCount(2), err)
+	})
+
+	t.Run("map", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"age", "cnt"})
+		rows.AddRow(int64(18), int64(2))
+		mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+
+		res, err := Project[map[string]any](NewSelector[TestModel](db).
+			Select(C("Age"), Count(C("Id")).As("cnt")).GroupBy(C("Age"))).GetMulti(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []map[string]any{{"age": int64(18), "cnt": int64(2)}}, res)
+	})
+
+	t.Run("no rows", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id"})
+		mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+
+		_, err := Project[int64](NewSelector[TestModel](db).Select(C("Id"))).GetMulti(context.Background())
+		assert.Equal(t, errs.ErrNoRows, err)
+	})
+}
+
+func TestProjector_Get(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+
+	rows := sqlmock.NewRows([]string{"cnt"})
+	rows.AddRow(3)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(id) AS cnt FROM test_model WHERE age>?;")).
+		WithArgs(18).WillReturnRows(rows)
+
+	res, err := Project[int64](NewSelector[TestModel](db).
+		Select(Count(C("Id")).As("cnt")).Where(C("Age").Gt(18))).Get(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), res)
+}
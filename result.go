@@ -24,3 +24,22 @@ func (r Result) RowsAffected() (int64, error) {
 func (r Result) Err() error {
 	return r.err
 }
+
+// 多条语句的执行结果，RowsAffected 是所有语句的和，LastInsertId 是最后一条语句的
+type multiResult []sql.Result
+
+func (m multiResult) LastInsertId() (int64, error) {
+	return m[len(m)-1].LastInsertId()
+}
+
+func (m multiResult) RowsAffected() (int64, error) {
+	var sum int64
+	for _, res := range m {
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		sum += n
+	}
+	return sum, nil
+}
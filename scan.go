@@ -0,0 +1,241 @@
+package orm
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"gitee.com/youkelike/orm/internal/errs"
+	"gitee.com/youkelike/orm/model"
+)
+
+// join 查询结果中的列别名格式：表别名__列名
+const joinColumnSep = "__"
+
+// ScanJoin 把 join 查询的结果映射到聚合结构体 R 中，
+// R 的每个字段（可以是组合、结构体或结构体指针）对应 join 中的一张表，
+// 字段通过标签 `orm:"table=t1"` 指定对应的表别名，没有标签时按字段类型匹配表的模型类型。
+// select 中带表名的列会被自动加上 别名__列名 形式的别名，每一行结果都是新创建的 R 实例。
+// outer join 中可能没有匹配的一侧要用结构体指针，这张表的列全是 NULL 时字段保持 nil，
+// 否则部分为 NULL 的列保持零值；结构体字段对应的表遇到 NULL 时和普通的 Scan 一样会返回错误。
+// 整个过程走的是正常的中间件流程。别名加在 s 的副本上，不会影响之后继续使用 s
+func ScanJoin[R any, T any](ctx context.Context, s *Selector[T]) ([]*R, error) {
+	typ := reflect.TypeOf(new(R)).Elem()
+	if typ.Kind() != reflect.Struct {
+		return nil, errs.ErrScanEntityValid
+	}
+
+	if err := s.checkLock(); err != nil {
+		return nil, err
+	}
+	s = s.Clone()
+	var err error
+	s.model, err = s.r.Get(new(T))
+	if err != nil {
+		return nil, err
+	}
+	s.joinScan = true
+
+	targets, err := s.joinTargets(typ)
+	if err != nil {
+		return nil, err
+	}
+
+	res := handle(ctx, &QueryContext{
+		Type:    "SELECT",
+		Builder: s,
+		Model:   s.model,
+		Sess:    s.sess,
+	}, scanJoinHandler[R](targets))
+	if res.Result != nil {
+		return res.Result.([]*R), res.Err
+	}
+	return nil, res.Err
+}
+
+// 聚合结构体中某个字段与 join 中某张表的对应关系
+type joinTarget struct {
+	// 字段在聚合结构体中的下标
+	index int
+	// 字段是否为结构体指针
+	ptr   bool
+	model *model.Model
+}
+
+// 找出 join 中每张表在聚合结构体中对应的字段，key 是表别名（没有别名时就是表名）
+func (s *Selector[T]) joinTargets(typ reflect.Type) (map[string]joinTarget, error) {
+	tables := s.joinTables(s.table)
+	targets := make(map[string]joinTarget, len(tables))
+	for i := 0; i < typ.NumField(); i++ {
+		fd := typ.Field(i)
+		ft := fd.Type
+		ptr := ft.Kind() == reflect.Pointer
+		if ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() != reflect.Struct {
+			continue
+		}
+
+		tag, err := joinTableTag(fd.Tag)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tables {
+			m, err := s.r.Get(t.entity)
+			if err != nil {
+				return nil, err
+			}
+			prefix := t.prefix(m)
+			if _, ok := targets[prefix]; ok {
+				continue
+			}
+			if tag != prefix && (tag != "" || reflect.TypeOf(t.entity).Elem() != ft) {
+				continue
+			}
+			fm, err := s.r.Get(reflect.New(ft).Interface())
+			if err != nil {
+				return nil, err
+			}
+			targets[prefix] = joinTarget{index: i, ptr: ptr, model: fm}
+			break
+		}
+	}
+	return targets, nil
+}
+
+// 按 from 子句中出现的顺序收集所有的表，没有指定 from 时就是 T 对应的表
+func (s *Selector[T]) joinTables(table TableReference) []Table {
+	switch t := table.(type) {
+	case nil:
+		return []Table{TableOf(new(T))}
+	case Table:
+		return []Table{t}
+	case Join:
+		return append(s.joinTables(t.left), s.joinTables(t.right)...)
+	default:
+		return nil
+	}
+}
+
+// `orm:"table=t1"`
+func joinTableTag(tag reflect.StructTag) (string, error) {
+	ormTag, ok := tag.Lookup("orm")
+	if !ok {
+		return "", nil
+	}
+	for _, pair := range strings.Split(ormTag, ",") {
+		segs := strings.Split(pair, "=")
+		if len(segs) != 2 {
+			return "", errs.NewInvalidTagContent(pair)
+		}
+		if segs[0] == "table" {
+			return segs[1], nil
+		}
+	}
+	return "", nil
+}
+
+func scanJoinHandler[R any](targets map[string]joinTarget) Handler {
+	return func(ctx context.Context, qc *QueryContext) *QueryResult {
+		q, err := qc.Builder.Build()
+		if err != nil {
+			return &QueryResult{
+				Err: err,
+			}
+		}
+
+		rows, err := qc.Sess.queryContext(ctx, q.SQL, q.Args...)
+		if err != nil {
+			return &QueryResult{
+				Err: err,
+			}
+		}
+		defer rows.Close()
+
+		cs, err := rows.Columns()
+		if err != nil {
+			return &QueryResult{
+				Err: err,
+			}
+		}
+		// 先把每一列对应到聚合结构体中的字段，扫描每一行时直接用
+		type colTarget struct {
+			target joinTarget
+			goName string
+			typ    reflect.Type
+		}
+		colTargets := make([]colTarget, 0, len(cs))
+		for _, c := range cs {
+			prefix, col, ok := strings.Cut(c, joinColumnSep)
+			if !ok {
+				return &QueryResult{
+					Err: errs.NewUnknownColumn(c),
+				}
+			}
+			target, ok := targets[prefix]
+			if !ok {
+				return &QueryResult{
+					Err: errs.NewUnknownColumn(c),
+				}
+			}
+			fd, ok := target.model.ColumnMap[col]
+			if !ok {
+				return &QueryResult{
+					Err: errs.NewUnknownColumn(c),
+				}
+			}
+			colTargets = append(colTargets, colTarget{target: target, goName: fd.GoName, typ: fd.Typ})
+		}
+
+		var tps []*R
+		for rows.Next() {
+			tp := new(R)
+			val := reflect.ValueOf(tp).Elem()
+			vals := make([]any, 0, len(colTargets))
+			for _, ct := range colTargets {
+				// 结构体指针先扫描到可以接收 NULL 的指针里，扫描完再决定要不要创建结构体
+				if ct.target.ptr {
+					vals = append(vals, reflect.New(reflect.PointerTo(ct.typ)).Interface())
+					continue
+				}
+				vals = append(vals, val.Field(ct.target.index).FieldByName(ct.goName).Addr().Interface())
+			}
+			if err = rows.Scan(vals...); err != nil {
+				return &QueryResult{
+					Err: err,
+				}
+			}
+			for i, ct := range colTargets {
+				if !ct.target.ptr {
+					continue
+				}
+				v := reflect.ValueOf(vals[i]).Elem()
+				if v.IsNil() {
+					continue
+				}
+				fv := val.Field(ct.target.index)
+				if fv.IsNil() {
+					fv.Set(reflect.New(fv.Type().Elem()))
+				}
+				fv.Elem().FieldByName(ct.goName).Set(v.Elem())
+			}
+			tps = append(tps, tp)
+		}
+		if err = rows.Err(); err != nil {
+			return &QueryResult{
+				Err: err,
+			}
+		}
+
+		if tps == nil {
+			return &QueryResult{
+				Err: errs.ErrNoRows,
+			}
+		}
+
+		return &QueryResult{
+			Result: tps,
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+
+	"gitee.com/youkelike/orm/internal/errs"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// join 结果解析方式二：
+// 用 ScanJoin 把结果映射到嵌套结构体，每张表对应聚合结构体中的一个字段
+func TestScanJoin(t *testing.T) {
+	type Order struct {
+		Id       int
+		UserName string
+	}
+
+	type OrderDetail struct {
+		OrderId int
+		ItemId  int
+		Address string
+	}
+
+	type Result struct {
+		Order
+		Detail *OrderDetail `orm:"table=t2"`
+	}
+
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+
+	t1 := TableOf(&Order{})
+	t2 := TableOf(&OrderDetail{}).As("t2")
+	join := t1.Join(t2).On(t1.C("Id").Eq(t2.C("OrderId")))
+
+	testCases := []struct {
+		name    string
+		s       *Selector[Order]
+		mock    func()
+		wantErr error
+		wantRes []*Result
+	}{
+		{
+			name: "all columns",
+			s:    NewSelector[Order](db).From(join),
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"order__id", "order__user_name", "t2__order_id", "t2__item_id", "t2__address"})
+				rows.AddRow(1, "alice", 1, 10, "guangzhou")
+				rows.AddRow(2, "bob", 2, 20, "shenzhen")
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT order.id AS order__id,order.user_name AS order__user_name," +
+					"t2.order_id AS t2__order_id,t2.item_id AS t2__item_id,t2.address AS t2__address " +
+					"FROM (order JOIN order_detail AS t2 ON order.id=t2.order_id);")).WillReturnRows(rows)
+			},
+			wantRes: []*Result{
+				{
+					Order:  Order{Id: 1, UserName: "alice"},
+					Detail: &OrderDetail{OrderId: 1, ItemId: 10, Address: "guangzhou"},
+				},
+				{
+					Order:  Order{Id: 2, UserName: "bob"},
+					Detail: &OrderDetail{OrderId: 2, ItemId: 20, Address: "shenzhen"},
+				},
+			},
+		},
+		{
+			name: "specify columns",
+			s:    NewSelector[Order](db).Select(t1.C("Id"), t2.C("Address")).From(join).Where(t1.C("Id").Gt(0)),
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"order__id", "t2__address"})
+				rows.AddRow(1, "guangzhou")
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT order.id AS order__id,t2.address AS t2__address " +
+					"FROM (order JOIN order_detail AS t2 ON order.id=t2.order_id) WHERE order.id>?;")).
+					WithArgs(0).WillReturnRows(rows)
+			},
+			wantRes: []*Result{
+				{
+					Order:  Order{Id: 1},
+					Detail: &OrderDetail{Address: "guangzhou"},
+				},
+			},
+		},
+		{
+			name:    "column without table",
+			s:       NewSelector[Order](db).Select(C("Id")).From(join),
+			mock:    func() {},
+			wantErr: errs.NewJoinColumnWithoutTable("Id"),
+		},
+		{
+			name: "unknown column",
+			s:    NewSelector[Order](db).From(join),
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"order__id", "t3__address"})
+				rows.AddRow(1, "guangzhou")
+				mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+			},
+			wantErr: errs.NewUnknownColumn("t3__address"),
+		},
+		{
+			name: "no rows",
+			s:    NewSelector[Order](db).From(join),
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"order__id"})
+				mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+			},
+			wantErr: errs.ErrNoRows,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.mock()
+			res, err := ScanJoin[Result](context.Background(), tc.s)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
+
+// ScanJoin 之后原来的 Selector 还能正常使用，不会带上 join 映射用的别名
+func TestScanJoin_KeepSelector(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+
+	t1 := TableOf(&TestModel{}).As("t1")
+	t2 := TableOf(&KeyModel{}).As("t2")
+	s := NewSelector[TestModel](db).Select(t1.C("Id"), t1.C("Age")).
+		From(t1.Join(t2).On(t1.C("Id").Eq(t2.C("Id"))))
+
+	type Result struct {
+		TestModel `orm:"table=t1"`
+	}
+	rows := sqlmock.NewRows([]string{"t1__id", "t1__age"})
+	rows.AddRow(1, 18)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT t1.id AS t1__id,t1.age AS t1__age " +
+		"FROM (test_model AS t1 JOIN key_model AS t2 ON t1.id=t2.id);")).WillReturnRows(rows)
+	res, err := ScanJoin[Result](context.Background(), s)
+	require.NoError(t, err)
+	assert.Equal(t, []*Result{{TestModel: TestModel{Id: 1, Age: 18}}}, res)
+
+	q, err := s.Build()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT t1.id,t1.age FROM (test_model AS t1 JOIN key_model AS t2 ON t1.id=t2.id);", q.SQL)
+	q, err = s.Clone().Build()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT t1.id,t1.age FROM (test_model AS t1 JOIN key_model AS t2 ON t1.id=t2.id);", q.SQL)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// outer join 中没有匹配的一侧用结构体指针接收，整张表的列都是 NULL 时保持 nil
+func TestScanJoin_LeftJoin(t *testing.T) {
+	type Order struct {
+		Id       int
+		UserName string
+	}
+
+	type OrderDetail struct {
+		OrderId int
+		ItemId  int
+		Address *sql.NullString
+	}
+
+	type Result struct {
+		Order
+		Detail *OrderDetail `orm:"table=t2"`
+	}
+
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+
+	t1 := TableOf(&Order{})
+	t2 := TableOf(&OrderDetail{}).As("t2")
+	s := NewSelector[Order](db).Select(t1.C("Id"), t2.C("OrderId"), t2.C("ItemId"), t2.C("Address")).
+		From(t1.LeftJoin(t2).On(t1.C("Id").Eq(t2.C("OrderId"))))
+
+	rows := sqlmock.NewRows([]string{"order__id", "t2__order_id", "t2__item_id", "t2__address"})
+	rows.AddRow(1, 1, 10, "guangzhou")
+	rows.AddRow(2, nil, nil, nil)
+	rows.AddRow(3, 3, 30, nil)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT order.id AS order__id,t2.order_id AS t2__order_id," +
+		"t2.item_id AS t2__item_id,t2.address AS t2__address " +
+		"FROM (order LEFT JOIN order_detail AS t2 ON order.id=t2.order_id);")).WillReturnRows(rows)
+
+	res, err := ScanJoin[Result](context.Background(), s)
+	require.NoError(t, err)
+	assert.Equal(t, []*Result{
+		{
+			Order:  Order{Id: 1},
+			Detail: &OrderDetail{OrderId: 1, ItemId: 10, Address: &sql.NullString{String: "guangzhou", Valid: true}},
+		},
+		{
+			Order: Order{Id: 2},
+		},
+		{
+			Order:  Order{Id: 3},
+			Detail: &OrderDetail{OrderId: 3, ItemId: 30},
+		},
+	}, res)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -2,11 +2,10 @@ package orm
 
 import (
 	"context"
-	"reflect"
 	"strconv"
-	"strings"
 
 	"gitee.com/youkelike/orm/internal/errs"
+	"gitee.com/youkelike/orm/model"
 )
 
 type Selectable interface {
@@ -18,7 +17,10 @@ type Selector[T any] struct {
 	sess Session
 
 	// select 子句
-	columns []Selectable
+	columns  []Selectable
+	distinct bool
+	// postgres 的 DISTINCT ON，每组只保留按 order by 排序后的第一行
+	distinctOn []Column
 	// from 子句
 	table TableReference
 	// where 子句
@@ -30,25 +32,46 @@ type Selector[T any] struct {
 	// having 子句
 	having []Predicate
 	// order 子句
-	orderBy []Column
+	orderBy []OrderBy
 	// offset 子句
 	offset int
 	// limit 子句
 	limit int
+
+	// 为 true 时给带表名的列自动加上 别名__列名 形式的别名，用于把 join 结果映射到嵌套结构体
+	joinScan bool
+
+	// 不为 nil 时给查询出来的实体记录快照
+	tracker *Tracker
+
+	// 加锁子句，UPDATE 或 SHARE
+	lock string
+	// 拿不到锁时的处理，NOWAIT 或 SKIP LOCKED，为空时等待
+	lockWait string
+
+	// 紧跟在 FROM 的表后面的索引提示
+	indexHints []indexHint
+	// 优化器提示，生成在 SELECT 后面
+	hint string
 }
 
 func NewSelector[T any](sess Session) *Selector[T] {
 	c := sess.getCore()
 	return &Selector[T]{
 		builder: builder{
-			r:      c.r,
-			quoter: c.dialect.quoter(),
+			r:       c.r,
+			dialect: c.dialect,
+			quoter:  c.dialect.quoter(),
 		},
 		sess: sess,
 	}
 }
 
 func (s *Selector[T]) Build() (*Query, error) {
+	if s.memo != nil {
+		return s.memo, nil
+	}
+	s.reset()
 	if s.model == nil {
 		var err error
 		s.model, err = s.r.Get(new(T))
@@ -58,34 +81,32 @@ func (s *Selector[T]) Build() (*Query, error) {
 	}
 
 	s.sb.WriteString("SELECT ")
+	s.buildOptimizerHint(s.sess.getCore().dialect, s.hint)
+	if err := s.buildDistinct(); err != nil {
+		return nil, err
+	}
 	err := s.buildColumns()
 	if err != nil {
 		return nil, err
 	}
 	s.sb.WriteString(" FROM ")
 
-	err = s.buildTable(s.table)
+	err = s.buildTable(s.sess.getCore().dialect, s.table)
 	if err != nil {
 		return nil, err
 	}
+	if err = s.buildIndexHints(); err != nil {
+		return nil, err
+	}
 	// if s.table == "" {
 	// 	s.sb.WriteString(s.model.TableName)
 	// } else {
 	// 	s.sb.WriteString(s.table)
 	// }
 
-	if len(s.where) > 0 {
-		s.sb.WriteString(" WHERE ")
-
-		// 先把切片形式的条件组装成链表
-		p := s.where[0]
-		for i := 1; i < len(s.where); i++ {
-			p = p.And(s.where[i])
-		}
-		// 遍历链表
-		if err := s.buildPredicate(p); err != nil {
-			return nil, err
-		}
+	// 切片形式的条件先组装成链表再遍历
+	if err = s.buildWhere(s.where); err != nil {
+		return nil, err
 	}
 
 	if len(s.groupBy) > 0 {
@@ -101,36 +122,20 @@ func (s *Selector[T]) Build() (*Query, error) {
 		}
 	}
 
-	if len(s.having) > 0 {
-		if len(s.groupBy) == 0 {
-			return nil, errs.ErrNoGroupUseHaving
-		}
-		s.sb.WriteString(" HAVING ")
-		// 先把切片形式的条件组装成链表
-		p := s.having[0]
-		for i := 1; i < len(s.having); i++ {
-			p = p.And(s.having[i])
-		}
-		// 遍历链表
-		if err := s.buildPredicate(p); err != nil {
-			return nil, err
-		}
+	// HAVING 和 ORDER BY 中可以引用 select 中声明的别名
+	s.aliases = s.selectAliases()
+	defer func() {
+		s.aliases = nil
+	}()
+	if !And(s.having...).empty() && len(s.groupBy) == 0 {
+		return nil, errs.ErrNoGroupUseHaving
+	}
+	if err = s.buildCondition("HAVING", s.having); err != nil {
+		return nil, err
 	}
 
-	if len(s.orderBy) > 0 {
-		s.sb.WriteString(" ORDER BY ")
-		for i, col := range s.orderBy {
-			if col.order == "" {
-				return nil, errs.ErrNoOrderByVerb
-			}
-			if i > 0 {
-				s.sb.WriteString(",")
-			}
-			err := s.buildColumn(col)
-			if err != nil {
-				return nil, err
-			}
-		}
+	if err = s.buildOrderBy(s.sess.getCore().dialect, s.orderBy); err != nil {
+		return nil, err
 	}
 
 	if s.offset > 0 {
@@ -145,85 +150,170 @@ func (s *Selector[T]) Build() (*Query, error) {
 		s.sb.WriteString(strconv.Itoa(s.limit))
 	}
 
+	if s.lock != "" {
+		c := s.sess.getCore()
+		err = c.dialect.buildLock(&s.builder, s.lock, s.lockWait)
+		if err != nil && !(err == errs.ErrUnsupportedLock && c.ignoreLock) {
+			return nil, err
+		}
+	}
+
 	s.sb.WriteString(";")
 
-	return &Query{
-		SQL:  s.sb.String(),
-		Args: s.args,
-	}, nil
+	return s.done(), nil
 }
 
-func (s *Selector[T]) buildTable(table TableReference) error {
-	switch t := table.(type) {
-	case nil:
-		s.sb.WriteString(s.model.TableName)
-	case Table:
-		m, err := s.r.Get(t.entity)
-		if err != nil {
-			return err
-		}
-		s.sb.WriteString(m.TableName)
-		if t.alias != "" {
-			s.sb.WriteString(" AS ")
-			s.sb.WriteString(t.alias)
+func (s *Selector[T]) buildIndexHints() error {
+	if len(s.indexHints) == 0 {
+		return nil
+	}
+	switch s.table.(type) {
+	case nil, Table:
+	default:
+		return errs.ErrIndexHintNotTable
+	}
+	return s.sess.getCore().dialect.buildIndexHints(&s.builder, s.indexHints)
+}
+
+func (s *Selector[T]) selectAliases() map[string]bool {
+	var aliases map[string]bool
+	for _, col := range s.columns {
+		alias := ""
+		switch c := col.(type) {
+		case Column:
+			alias = c.alias
+		case Aggregate:
+			alias = c.alias
+		case FullText:
+			alias = c.alias
 		}
-	case Join:
-		s.sb.WriteString("(")
-		err := s.buildTable(t.left)
-		if err != nil {
-			return err
+		if alias == "" {
+			continue
 		}
-		s.sb.WriteString(" " + t.typ + " ")
-		err = s.buildTable(t.right)
-		if err != nil {
-			return err
+		if aliases == nil {
+			aliases = make(map[string]bool)
 		}
+		aliases[alias] = true
+	}
+	return aliases
+}
 
-		if len(t.using) > 0 {
-			s.sb.WriteString(" USING (")
-			for i, col := range t.using {
-				if i > 0 {
-					s.sb.WriteString(",")
-				}
-				err := s.buildColumn(Column{name: col})
-				if err != nil {
-					return err
-				}
+// AsSubquery 把当前查询当作子查询，外层查询的类型参数不需要和 T 一致
+func (s *Selector[T]) AsSubquery(alias string) SubqueryTable {
+	return SubqueryTable{src: s, as: alias}
+}
+
+// 作为子查询时结果集中的列：有别名的列只能用别名引用，没别名的列用字段名引用；
+// SELECT * 时是 FROM 的表的全部字段
+func (s *Selector[T]) outputColumns() (map[string]string, bool, error) {
+	m, err := s.r.Get(new(T))
+	if err != nil {
+		return nil, false, err
+	}
+	if len(s.columns) == 0 {
+		switch t := s.table.(type) {
+		case nil:
+		case Table:
+			if m, err = s.r.Get(t.entity); err != nil {
+				return nil, false, err
 			}
-			s.sb.WriteString(")")
+		default:
+			return fieldColumns(m, nil), false, nil
 		}
+		return fieldColumns(m, nil), true, nil
+	}
 
-		if len(t.on) > 0 {
-			s.sb.WriteString(" ON ")
-			p := t.on[0]
-			for i := 1; i < len(t.on); i++ {
-				p = p.And(t.on[i])
+	cols := make(map[string]string, len(s.columns))
+	known := true
+	for _, col := range s.columns {
+		switch c := col.(type) {
+		case Column:
+			if c.alias != "" {
+				cols[c.alias] = c.alias
+				continue
 			}
-			if err := s.buildPredicate(p); err != nil {
-				return err
+			name, err := s.resultColumn(m, c)
+			if err != nil {
+				return nil, false, err
 			}
+			cols[c.name] = name
+		case Aggregate:
+			if c.alias != "" {
+				cols[c.alias] = c.alias
+			}
+		default:
+			known = false
 		}
-		s.sb.WriteString(")")
-	case Subquery[T]:
-		res, err := t.builder.Build()
-		if err != nil {
-			return err
-		}
+	}
+	if !known {
+		// 原生表达式中可能选中了模型的列
+		cols = fieldColumns(m, cols)
+	}
+	return cols, known, nil
+}
 
-		s.addArgs(res.Args...)
-		s.sb.WriteString("(")
-		s.sb.WriteString(strings.Trim(res.SQL, ";"))
-		s.sb.WriteString(")")
-		s.sb.WriteString(" AS ")
-		s.sb.WriteString(t.as)
+// 列在结果集中的名字，m 是没有限定表的列所属的模型
+func (s *Selector[T]) resultColumn(m *model.Model, c Column) (string, error) {
+	switch t := c.table.(type) {
+	case nil:
+	case Table:
+		var err error
+		if m, err = s.r.Get(t.entity); err != nil {
+			return "", err
+		}
+	case derivedTable:
+		return t.derived().column(c.name)
 	default:
-		return errs.NewUnsupportTable(table)
+		return "", errs.NewUnsupportTable(t)
+	}
+	fd, ok := m.FieldMap[c.name]
+	if !ok {
+		return "", errs.NewUnknownField(c.name)
+	}
+	return fd.ColName, nil
+}
+
+// 把模型的字段名到列名的映射加到 cols 中，已经有的不覆盖
+func fieldColumns(m *model.Model, cols map[string]string) map[string]string {
+	if cols == nil {
+		cols = make(map[string]string, len(m.Fields))
+	}
+	for _, fd := range m.Fields {
+		if _, ok := cols[fd.GoName]; !ok {
+			cols[fd.GoName] = fd.ColName
+		}
+	}
+	return cols
+}
+
+func (s *Selector[T]) buildDistinct() error {
+	if len(s.distinctOn) > 0 {
+		if !s.sess.getCore().dialect.supportDistinctOn() {
+			return errs.ErrUnsupportedDistinctOn
+		}
+		s.sb.WriteString("DISTINCT ON (")
+		for i, col := range s.distinctOn {
+			if i > 0 {
+				s.sb.WriteString(",")
+			}
+			if err := s.buildColumn(col); err != nil {
+				return err
+			}
+		}
+		s.sb.WriteString(") ")
+		return nil
+	}
+	if s.distinct {
+		s.sb.WriteString("DISTINCT ")
 	}
 	return nil
 }
 
 func (s *Selector[T]) buildColumns() error {
 	if len(s.columns) == 0 {
+		if s.joinScan {
+			return s.buildJoinColumns()
+		}
 		s.sb.WriteString("*")
 		return nil
 	}
@@ -234,18 +324,25 @@ func (s *Selector[T]) buildColumns() error {
 		}
 		switch c := col.(type) {
 		case Column:
-			err := s.buildColumn(col.(Column))
+			if s.joinScan {
+				alias, err := s.joinColumnAlias(c)
+				if err != nil {
+					return err
+				}
+				c.alias = alias
+			}
+			err := s.buildColumn(c)
 			if err != nil {
 				return err
 			}
 		case Aggregate:
-			s.sb.WriteString(c.fn)
-			s.sb.WriteString("(")
-			err := s.buildColumn(c.arg)
-			if err != nil {
+			if err := s.buildAggregate(c); err != nil {
+				return err
+			}
+		case FullText:
+			if err := s.buildExpresssion(c); err != nil {
 				return err
 			}
-			s.sb.WriteString(")")
 			if c.alias != "" {
 				s.sb.WriteString(" AS ")
 				s.sb.WriteString(c.alias)
@@ -258,21 +355,94 @@ func (s *Selector[T]) buildColumns() error {
 	return nil
 }
 
+// 没有指定列时，把 join 中所有表的列都展开，并加上 别名__列名 形式的别名
+func (s *Selector[T]) buildJoinColumns() error {
+	for i, t := range s.joinTables(s.table) {
+		m, err := s.r.Get(t.entity)
+		if err != nil {
+			return err
+		}
+		prefix := t.prefix(m)
+		for j, fd := range m.Fields {
+			if i > 0 || j > 0 {
+				s.sb.WriteString(",")
+			}
+			s.sb.WriteString(prefix)
+			s.sb.WriteString(".")
+			s.sb.WriteString(fd.ColName)
+			s.sb.WriteString(" AS ")
+			s.sb.WriteString(prefix + joinColumnSep + fd.ColName)
+		}
+	}
+	return nil
+}
+
+func (s *Selector[T]) joinColumnAlias(c Column) (string, error) {
+	t, ok := c.table.(Table)
+	if !ok {
+		return "", errs.NewJoinColumnWithoutTable(c.name)
+	}
+	m, err := s.r.Get(t.entity)
+	if err != nil {
+		return "", err
+	}
+	fd, ok := m.FieldMap[c.name]
+	if !ok {
+		return "", errs.NewUnknownField(c.name)
+	}
+	return t.prefix(m) + joinColumnSep + fd.ColName, nil
+}
+
 func (s *Selector[T]) Select(cols ...Selectable) *Selector[T] {
 	s.columns = cols
 	return s
 }
 
+// Distinct 去掉重复的行
+func (s *Selector[T]) Distinct() *Selector[T] {
+	s.distinct = true
+	return s
+}
+
+// DistinctOn 按指定的列去重，每组保留 order by 排序后的第一行，order by 要以这些列开头，
+// 只有 postgres 支持
+func (s *Selector[T]) DistinctOn(cols ...Column) *Selector[T] {
+	s.distinctOn = cols
+	return s
+}
+
 func (s *Selector[T]) From(table TableReference) *Selector[T] {
 	s.table = table
 	return s
 }
 
+// Where 会替换掉之前设置的条件，分步骤构造条件时用 AndWhere、OrWhere、WhereIf
 func (s *Selector[T]) Where(ps ...Predicate) *Selector[T] {
 	s.where = ps
 	return s
 }
 
+// AndWhere 追加条件，和之前的条件用 AND 连接
+func (s *Selector[T]) AndWhere(ps ...Predicate) *Selector[T] {
+	s.where = andWhere(s.where, ps)
+	return s
+}
+
+// OrWhere 把之前的条件作为一个整体，和 ps 用 OR 连接，ps 之间是 AND：
+// Where(a, b).OrWhere(c, d) 生成 (a AND b) OR (c AND d)
+func (s *Selector[T]) OrWhere(ps ...Predicate) *Selector[T] {
+	s.where = orWhere(s.where, ps)
+	return s
+}
+
+// WhereIf 在 cond 为 true 时追加条件，相当于 AndWhere
+func (s *Selector[T]) WhereIf(cond bool, ps ...Predicate) *Selector[T] {
+	if cond {
+		return s.AndWhere(ps...)
+	}
+	return s
+}
+
 func (s *Selector[T]) GroupBy(cols ...Column) *Selector[T] {
 	s.groupBy = cols
 	return s
@@ -283,10 +453,66 @@ func (s *Selector[T]) Having(ps ...Predicate) *Selector[T] {
 	return s
 }
 
-func (s *Selector[T]) OrderBy(cols ...Column) *Selector[T] {
-	s.orderBy = cols
+func (s *Selector[T]) OrderBy(items ...Orderable) *Selector[T] {
+	s.orderBy = orderBys(items)
+	return s
+}
+
+// Track 给 Get、GetMulti 查询出来的实体记录快照，之后用同一个 Tracker 的 Updater 只会更新变化了的列
+func (s *Selector[T]) Track(t *Tracker) *Selector[T] {
+	s.tracker = t
+	return s
+}
+
+// ForUpdate 给查询到的行加排他锁，只能在事务中使用，例如从任务队列里取任务：
+//
+//	NewSelector[Job](tx).Where(C("Status").Eq(0)).Limit(10).ForUpdate().SkipLocked().GetMulti(ctx)
+func (s *Selector[T]) ForUpdate() *Selector[T] {
+	s.lock = "UPDATE"
 	return s
 }
+
+// ForShare 给查询到的行加共享锁，只能在事务中使用，mysql 需要 8.0 以上的版本
+func (s *Selector[T]) ForShare() *Selector[T] {
+	s.lock = "SHARE"
+	return s
+}
+
+// NoWait 拿不到锁时立刻返回错误，需要和 ForUpdate 或 ForShare 一起用
+func (s *Selector[T]) NoWait() *Selector[T] {
+	s.lockWait = "NOWAIT"
+	return s
+}
+
+// SkipLocked 跳过被别的事务锁住的行，需要和 ForUpdate 或 ForShare 一起用
+func (s *Selector[T]) SkipLocked() *Selector[T] {
+	s.lockWait = "SKIP LOCKED"
+	return s
+}
+
+// UseIndex、ForceIndex、IgnoreIndex 生成 mysql 的索引提示，只能用在单表查询上，其他方言会返回错误
+func (s *Selector[T]) UseIndex(indexes ...string) *Selector[T] {
+	s.indexHints = append(s.indexHints, indexHint{typ: "USE", indexes: indexes})
+	return s
+}
+
+func (s *Selector[T]) ForceIndex(indexes ...string) *Selector[T] {
+	s.indexHints = append(s.indexHints, indexHint{typ: "FORCE", indexes: indexes})
+	return s
+}
+
+func (s *Selector[T]) IgnoreIndex(indexes ...string) *Selector[T] {
+	s.indexHints = append(s.indexHints, indexHint{typ: "IGNORE", indexes: indexes})
+	return s
+}
+
+// OptimizerHint 在 SELECT 后面生成 /*+ hint */，例如 OptimizerHint("MAX_EXECUTION_TIME(1000)")，
+// 不支持的方言会忽略
+func (s *Selector[T]) OptimizerHint(hint string) *Selector[T] {
+	s.hint = hint
+	return s
+}
+
 func (s *Selector[T]) Limit(val int) *Selector[T] {
 	s.limit = val
 	return s
@@ -296,72 +522,190 @@ func (s *Selector[T]) Offset(val int) *Selector[T] {
 	return s
 }
 
-// join 查询的结果处理
-func (s *Selector[T]) Scan(entity any) (ret []any, err error) {
-	typ := reflect.TypeOf(entity)
-	if typ.Kind() != reflect.Pointer || typ.Elem().Kind() != reflect.Struct {
-		return nil, errs.ErrScanEntityValid
-	}
-
-	model, err := s.r.Get(entity)
-	if err != nil {
-		return nil, err
-	}
-
-	q, err := s.Build()
-	if err != nil {
+func (s *Selector[T]) Get(ctx context.Context) (*T, error) {
+	if err := s.checkLock(); err != nil {
 		return nil, err
 	}
-
-	rows, err := s.sess.queryContext(context.Background(), q.SQL, q.Args...)
+	var err error
+	s.model, err = s.r.Get(new(T))
 	if err != nil {
 		return nil, err
 	}
 
-	for rows.Next() {
-		val := s.sess.getCore().creator(model, entity)
-		err = val.SetColumns(rows)
-		ret = append(ret, entity)
+	res := get[T](ctx, &QueryContext{
+		Type:    "SELECT",
+		Builder: s,
+		Model:   s.model,
+		Sess:    s.sess,
+	})
+	if res.Result != nil {
+		tp := res.Result.(*T)
+		if res.Err == nil && s.tracker != nil {
+			if err = s.track(tp); err != nil {
+				return nil, err
+			}
+		}
+		return tp, res.Err
 	}
-
-	return ret, err
+	return nil, res.Err
 }
 
-func (s *Selector[T]) Get(ctx context.Context) (*T, error) {
+func (s *Selector[T]) GetMulti(ctx context.Context) ([]*T, error) {
+	if err := s.checkLock(); err != nil {
+		return nil, err
+	}
 	var err error
 	s.model, err = s.r.Get(new(T))
 	if err != nil {
 		return nil, err
 	}
 
-	res := get[T](ctx, &QueryContext{
+	// 这样改写是为了加入 middleware 功能
+	res := getMulti[T](ctx, &QueryContext{
 		Type:    "SELECT",
 		Builder: s,
 		Model:   s.model,
 		Sess:    s.sess,
 	})
 	if res.Result != nil {
-		return res.Result.(*T), res.Err
+		tps := res.Result.([]*T)
+		if res.Err == nil && s.tracker != nil {
+			for _, tp := range tps {
+				if err = s.track(tp); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return tps, res.Err
 	}
 	return nil, res.Err
 }
 
-func (s *Selector[T]) GetMulti(ctx context.Context) ([]*T, error) {
+// 加锁只在事务中有意义，自动提交的语句执行完锁就释放了。
+// Build 只负责生成语句，所以在执行前检查
+func (s *Selector[T]) checkLock() error {
+	if _, ok := s.sess.(*DB); ok && s.lock != "" {
+		return errs.ErrLockOutsideTx
+	}
+	return nil
+}
+
+func (s *Selector[T]) track(tp *T) error {
+	return s.tracker.track(s.model, s.sess.getCore().creator(s.model, tp), tp)
+}
+
+// Iter 以迭代器的形式返回查询结果，*sql.Rows 会一直持有到调用 Iterator.Close 为止
+func (s *Selector[T]) Iter(ctx context.Context) (*Iterator[T], error) {
+	if err := s.checkLock(); err != nil {
+		return nil, err
+	}
 	var err error
 	s.model, err = s.r.Get(new(T))
 	if err != nil {
 		return nil, err
 	}
 
-	// 这样改写是为了加入 middleware 功能
-	res := getMulti[T](ctx, &QueryContext{
+	res := iter[T](ctx, &QueryContext{
 		Type:    "SELECT",
 		Builder: s,
 		Model:   s.model,
 		Sess:    s.sess,
 	})
 	if res.Result != nil {
-		return res.Result.([]*T), res.Err
+		return res.Result.(*Iterator[T]), res.Err
 	}
 	return nil, res.Err
 }
+
+// 基于当前的 from、where、group by、having 构造一个只查询 cols 的新 Selector，不会影响原来的 Selector。
+// 不带加锁子句，聚合查询不能加锁，返回行的派生查询要自己带上
+func (s *Selector[T]) derive(cols ...Selectable) *Selector[T] {
+	return &Selector[T]{
+		builder: s.builder.clone(),
+		sess:    s.sess,
+		columns: cols,
+		table:   s.table,
+		where:   s.where,
+		groupBy: s.groupBy,
+		having:  s.having,
+		// 提示影响的是 where 怎么走索引，派生出来的查询也要带上
+		indexHints: s.indexHints,
+		hint:       s.hint,
+	}
+}
+
+// Clone 复制出一个独立的查询，之后对任意一方调用的方法都不会影响另一方，
+// 可以先构造公共的部分，再分别加上不同的条件。
+// 同一个 Selector 不能在多个 goroutine 里同时使用，需要并发时各自 Clone 一份
+func (s *Selector[T]) Clone() *Selector[T] {
+	c := *s
+	c.builder = s.builder.clone()
+	c.columns = append([]Selectable(nil), s.columns...)
+	c.distinctOn = append([]Column(nil), s.distinctOn...)
+	c.where = append([]Predicate(nil), s.where...)
+	c.groupBy = append([]Column(nil), s.groupBy...)
+	c.having = append([]Predicate(nil), s.having...)
+	c.orderBy = append([]OrderBy(nil), s.orderBy...)
+	c.indexHints = append([]indexHint(nil), s.indexHints...)
+	return &c
+}
+
+// Count 统计满足当前条件的行数，忽略 order by、offset、limit，
+// 有 group by 时统计的是分组数，有 distinct 时统计的是去重后的行数
+func (s *Selector[T]) Count(ctx context.Context) (int64, error) {
+	sub := s.derive(Raw("COUNT(*)"))
+	if s.distinct || len(s.distinctOn) > 0 {
+		inner := s.derive(s.columns...)
+		inner.distinct = s.distinct
+		inner.distinctOn = s.distinctOn
+		// DISTINCT ON 保留哪一行取决于排序
+		inner.orderBy = s.orderBy
+		sub.table = SubqueryOf(inner).As("t")
+		sub.where = nil
+		sub.groupBy = nil
+		sub.having = nil
+		sub.indexHints = nil
+	} else if len(s.groupBy) > 0 {
+		cols := make([]Selectable, 0, len(s.groupBy))
+		for _, col := range s.groupBy {
+			cols = append(cols, col)
+		}
+		sub.table = SubqueryOf(s.derive(cols...)).As("t")
+		sub.where = nil
+		sub.groupBy = nil
+		sub.having = nil
+		sub.indexHints = nil
+	}
+	return Project[int64](sub).Get(ctx)
+}
+
+// Exists 判断是否存在满足当前条件的行
+func (s *Selector[T]) Exists(ctx context.Context) (bool, error) {
+	sub := s.derive(Raw("1"))
+	sub.limit = 1
+	_, err := Project[int64](sub).Get(ctx)
+	if err == errs.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Pluck 查询满足当前条件的某一列，保留 distinct、order by、offset、limit 和加锁子句
+func Pluck[V any, T any](ctx context.Context, s *Selector[T], col Column) ([]V, error) {
+	sub := s.derive(col)
+	sub.distinct = s.distinct
+	sub.orderBy = s.orderBy
+	sub.offset = s.offset
+	sub.limit = s.limit
+	sub.lock, sub.lockWait = s.lock, s.lockWait
+	return Project[V](sub).GetMulti(ctx)
+}
+
+// AggregateValue 对满足当前条件的所有行计算聚合函数，忽略 group by、having、order by、offset、limit，
+// 没有数据时 SUM、AVG、MAX、MIN 返回 NULL，这种情况下 V 要用 sql.NullInt64 之类的类型
+func AggregateValue[V any, T any](ctx context.Context, s *Selector[T], agg Aggregate) (V, error) {
+	sub := s.derive(agg)
+	sub.groupBy = nil
+	sub.having = nil
+	return Project[V](sub).Get(ctx)
+}
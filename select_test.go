@@ -3,7 +3,10 @@ package orm
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"regexp"
 	"testing"
 
 	"gitee.com/youkelike/orm/internal/errs"
@@ -275,6 +278,92 @@ func TestSelector_Select(t *testing.T) {
 				SQL: "SELECT SUM(age),COUNT(first_name) FROM test_model;",
 			},
 		},
+		{
+			name: "count distinct",
+			s:    NewSelector[TestModel](db).Select(Count(C("FirstName")).Distinct().As("cnt")),
+			wantQuery: &Query{
+				SQL: "SELECT COUNT(DISTINCT first_name) AS cnt FROM test_model;",
+			},
+		},
+		{
+			name: "distinct",
+			s:    NewSelector[TestModel](db).Select(C("Age")).Distinct(),
+			wantQuery: &Query{
+				SQL: "SELECT DISTINCT age FROM test_model;",
+			},
+		},
+		{
+			name:    "distinct on unsupported",
+			s:       NewSelector[TestModel](db).DistinctOn(C("Age")),
+			wantErr: errs.ErrUnsupportedDistinctOn,
+		},
+		{
+			name: "having aggregate",
+			s: NewSelector[TestModel](db).Select(C("Age"), Count(C("Id"))).GroupBy(C("Age")).
+				Having(Count(C("Id")).Gt(5)),
+			wantQuery: &Query{
+				SQL:  "SELECT age,COUNT(id) FROM test_model GROUP BY age HAVING COUNT(id)>?;",
+				Args: []any{5},
+			},
+		},
+		{
+			name: "having aggregate comparisons",
+			s: NewSelector[TestModel](db).Select(C("Age"), Count(C("Id"))).GroupBy(C("Age")).
+				Having(Count(C("Id")).GtEq(5), Sum(C("Age")).LtEq(100), Min(C("Age")).NotEq(0)),
+			wantQuery: &Query{
+				SQL:  "SELECT age,COUNT(id) FROM test_model GROUP BY age HAVING ((COUNT(id)>=?) AND (SUM(age)<=?)) AND (MIN(age)!=?);",
+				Args: []any{5, 100, 0},
+			},
+		},
+		{
+			name: "having alias",
+			s: NewSelector[TestModel](db).Select(C("FirstName"), Avg(C("Age")).As("avg_age")).GroupBy(C("FirstName")).
+				Having(C("avg_age").Gt(18)),
+			wantQuery: &Query{
+				SQL:  "SELECT first_name,AVG(age) AS avg_age FROM test_model GROUP BY first_name HAVING avg_age>?;",
+				Args: []any{18},
+			},
+		},
+		{
+			name:    "alias in where",
+			s:       NewSelector[TestModel](db).Select(Avg(C("Age")).As("avg_age")).Where(C("avg_age").Gt(18)),
+			wantErr: errs.NewUnknownField("avg_age"),
+		},
+		{
+			name: "order by aggregate and alias",
+			s: NewSelector[TestModel](db).Select(C("Age"), Count(C("Id")).As("cnt")).GroupBy(C("Age")).
+				OrderBy(Max(C("Id")).Desc(), C("cnt").Asc(), Desc(Raw("FIELD(age,?,?)", 18, 20))),
+			wantQuery: &Query{
+				SQL:  "SELECT age,COUNT(id) AS cnt FROM test_model GROUP BY age ORDER BY MAX(id) DESC,cnt ASC,(FIELD(age,?,?)) DESC;",
+				Args: []any{18, 20},
+			},
+		},
+		{
+			name: "order by without verb",
+			s:    NewSelector[TestModel](db).OrderBy(Max(C("Id")), C("Age")),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM test_model ORDER BY MAX(id),age;",
+			},
+		},
+		{
+			name: "order by nulls",
+			s:    NewSelector[TestModel](db).OrderBy(C("LastName").Asc().NullsLast(), C("Age").Desc().NullsFirst()),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM test_model ORDER BY ISNULL(last_name),last_name ASC,ISNULL(age) DESC,age DESC;",
+			},
+		},
+		{
+			name: "order by table column",
+			s: func() QueryBuilder {
+				t1 := TableOf(&TestModel{}).As("t1")
+				t2 := TableOf(&KeyModel{}).As("t2")
+				return NewSelector[TestModel](db).From(t1.Join(t2).On(t1.C("Id").Eq(t2.C("Id")))).
+					OrderBy(t2.C("Name").Desc(), t1.C("Id"))
+			}(),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM (test_model AS t1 JOIN key_model AS t2 ON t1.id=t2.id) ORDER BY t2.name DESC,t1.id;",
+			},
+		},
 		{
 			name:    "Sum invalid",
 			s:       NewSelector[TestModel](db).Select(Sum(C("XXX"))),
@@ -484,242 +573,1047 @@ func TestSelector_Join(t *testing.T) {
 	}
 }
 
-// join 结果解析方式一：
-// 正常构造 sql，但最后用 Scan 方法来解析结果，它脱离了内置的中间件流程
-func TestSelector_Scan(t *testing.T) {
+func TestSelector_JoinTypes(t *testing.T) {
+	mysqlDB := memoryDB(t)
+	postgresDB := memoryDB(t, DBWithDialect(DialectPostgreSQL))
+	sqliteDB := memoryDB(t, DBWithDialect(DialectSQLite))
 	type Order struct {
-		Id        int
-		UserName  string
-		UsingCol1 string
-		UsingCol2 string
+		Id     int
+		UserId int
 	}
 
 	type OrderDetail struct {
 		OrderId int
 		ItemId  int
-		Address string
 		Price   int
 	}
 
-	type Result struct {
-		Id      int
-		ItemId  int
-		Address string
-		Price   int
+	t1 := TableOf(&Order{}).As("t1")
+	t2 := TableOf(&OrderDetail{}).As("t2")
+	lateral := func(db *DB) Subquery[OrderDetail] {
+		return SubqueryOf(NewSelector[OrderDetail](db).Select(C("ItemId"), Max(C("Price")).As("top")).
+			Where(C("OrderId").Eq(t1.C("Id"))).GroupBy(C("ItemId"))).As("d")
 	}
 
-	mockDB, mock, err := sqlmock.New()
-	require.NoError(t, err)
-	db, err := OpenDB(mockDB)
-	require.NoError(t, err)
-
-	rows := sqlmock.NewRows([]string{"id", "item_id", "address"})
-	rows.AddRow(1, 1, "guangzhou")
-	mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
-
-	rows = sqlmock.NewRows([]string{"id", "item_id", "address", "price", "user_name"})
-	rows.AddRow(1, 1, "guangzhou", 100, "alice")
-	mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
-
-	rows = sqlmock.NewRows([]string{"id", "item_id", "address", "price"})
-	rows.AddRow(1, 1, "guangzhou", 100)
-	mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
-
 	testCases := []struct {
-		name    string
-		entity  any
-		s       *Selector[Order]
-		wantErr error
-		wantRes []any
+		name      string
+		s         QueryBuilder
+		wantQuery *Query
+		wantErr   error
 	}{
 		{
-			name:    "no pointer",
-			entity:  Result{},
-			wantErr: errs.ErrScanEntityValid,
-			wantRes: nil,
+			name: "full join",
+			s: NewSelector[Order](postgresDB).
+				From(t1.FullJoin(t2).On(t1.C("Id").Eq(t2.C("OrderId")))),
+			wantQuery: &Query{
+				SQL: `SELECT * FROM (order AS t1 FULL JOIN order_detail AS t2 ON t1.id=t2.order_id);`,
+			},
+		},
+		{
+			name: "mysql full join",
+			s: NewSelector[Order](mysqlDB).
+				From(t1.FullJoin(t2).On(t1.C("Id").Eq(t2.C("OrderId")))),
+			wantErr: errs.NewUnsupportedJoin("FULL JOIN"),
 		},
 		{
-			name: "struct fields more than query results",
-			s: func() *Selector[Order] {
-				t1 := TableOf(&Order{}).As("t1")
-				t2 := TableOf(&OrderDetail{}).As("t2")
-				t3 := t1.RightJoin(t2).On(t1.C("Id").Eq(t2.C("OrderId")))
-				return NewSelector[Order](db).
-					Select(t1.C("Id"), t2.C("ItemId"), t2.C("Price")).
-					From(t3)
+			name: "cross join",
+			s:    NewSelector[Order](mysqlDB).From(t1.CrossJoin(t2)),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM (order AS t1 CROSS JOIN order_detail AS t2);",
+			},
+		},
+		{
+			name: "natural join chain",
+			s: NewSelector[Order](mysqlDB).
+				From(t1.NaturalJoin(t2).LeftJoin(TableOf(&Order{}).As("t3")).Using("UserId")),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM ((order AS t1 NATURAL JOIN order_detail AS t2) LEFT JOIN order AS t3 USING (user_id));",
+			},
+		},
+		{
+			name: "subquery starts join",
+			s: func() QueryBuilder {
+				sub := SubqueryOf(NewSelector[OrderDetail](mysqlDB).Select(C("OrderId"), Sum(C("Price")).As("total")).
+					GroupBy(C("OrderId"))).As("s")
+				return NewSelector[Order](mysqlDB).Select(t1.C("UserId"), sub.C("total")).
+					From(sub.Join(t1).On(sub.C("OrderId").Eq(t1.C("Id")))).
+					Where(sub.C("total").Gt(100))
 			}(),
-			entity:  &Result{},
-			wantErr: nil,
-			wantRes: []any{
-				&Result{
-					Id:      1,
-					ItemId:  1,
-					Address: "guangzhou",
-					Price:   0,
-				},
+			wantQuery: &Query{
+				SQL:  "SELECT t1.user_id,s.total FROM ((SELECT order_id,SUM(price) AS total FROM order_detail GROUP BY order_id) AS s JOIN order AS t1 ON s.order_id=t1.id) WHERE s.total>?;",
+				Args: []any{100},
 			},
 		},
 		{
-			name: "struct fields less than query results",
-			s: func() *Selector[Order] {
-				t1 := TableOf(&Order{}).As("t1")
-				t2 := TableOf(&OrderDetail{}).As("t2")
-				t3 := t1.RightJoin(t2).On(t1.C("Id").Eq(t2.C("OrderId")))
-				return NewSelector[Order](db).
-					Select(t1.C("Id"), t2.C("ItemId"), t2.C("Price"), t2.C("Address"), t1.C("UserName")).
-					From(t3)
+			name: "cross join lateral",
+			s: func() QueryBuilder {
+				d := lateral(postgresDB)
+				return NewSelector[Order](postgresDB).Select(t1.C("Id"), d.C("ItemId"), d.C("top")).
+					From(t1.CrossJoinLateral(d))
 			}(),
-			entity:  &Result{},
-			wantErr: errs.NewUnknownColumn("user_name"),
+			wantQuery: &Query{
+				SQL: "SELECT t1.id,d.item_id,d.top FROM (order AS t1 CROSS JOIN LATERAL (SELECT item_id,MAX(price) AS top FROM order_detail WHERE order_id=t1.id GROUP BY item_id) AS d);",
+			},
 		},
 		{
-			name: "default",
-			s: func() *Selector[Order] {
-				t1 := TableOf(&Order{}).As("t1")
-				t2 := TableOf(&OrderDetail{}).As("t2")
-				t3 := t1.RightJoin(t2).On(t1.C("Id").Eq(t2.C("OrderId")))
-				return NewSelector[Order](db).
-					Select(t1.C("Id"), t2.C("ItemId"), t2.C("Price"), t2.C("Address")).
-					From(t3)
+			name: "left join lateral",
+			s: func() QueryBuilder {
+				d := lateral(mysqlDB)
+				return NewSelector[Order](mysqlDB).
+					From(t1.LeftJoinLateral(d).On(d.C("top").Gt(10)))
 			}(),
-			entity:  &Result{},
-			wantErr: nil,
-			wantRes: []any{
-				&Result{
-					Id:      1,
-					ItemId:  1,
-					Address: "guangzhou",
-					Price:   100,
-				},
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM (order AS t1 LEFT JOIN LATERAL (SELECT item_id,MAX(price) AS top FROM order_detail WHERE order_id=t1.id GROUP BY item_id) AS d ON d.top>?);",
+				Args: []any{10},
 			},
 		},
+		{
+			name:    "sqlite lateral",
+			s:       NewSelector[Order](sqliteDB).From(t1.CrossJoinLateral(lateral(sqliteDB))),
+			wantErr: errs.NewUnsupportedJoin("CROSS JOIN LATERAL"),
+		},
+		{
+			name:    "lateral table",
+			s:       NewSelector[Order](postgresDB).From(t1.JoinLateral(t2).On(t1.C("Id").Eq(t2.C("OrderId")))),
+			wantErr: errs.ErrLateralNotSubquery,
+		},
 	}
+
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-
-			res, err := tc.s.Scan(tc.entity)
+			q, err := tc.s.Build()
 			assert.Equal(t, tc.wantErr, err)
 			if err != nil {
 				return
 			}
-			assert.Equal(t, tc.wantRes, res)
+			assert.Equal(t, tc.wantQuery, q)
 		})
 	}
 }
 
-// join 结果解析方式二：
-// 给最开始的 T 传 Result，要求构造 sql 的过程中所有出现列的地方都要带上表名
-func TestSelectorJoin_GetMulti(t *testing.T) {
+func TestSelector_SubqueryTable(t *testing.T) {
+	db := memoryDB(t)
 	type Order struct {
-		Id        int
-		UserName  string
-		UsingCol1 string
-		UsingCol2 string
+		Id     int
+		UserId int
+		Amount int
 	}
 
-	type OrderDetail struct {
-		OrderId int
-		ItemId  int
-		Address string
-		Price   int
+	type User struct {
+		Id   int
+		Name string
 	}
 
-	type Result struct {
-		Id      int
-		ItemId  int
-		Address string
-		Price   int
+	type Report struct {
+		UserId int
+		Total  int
 	}
 
-	mockDB, mock, err := sqlmock.New()
-	require.NoError(t, err)
-	db, err := OpenDB(mockDB)
-	require.NoError(t, err)
-
-	rows := sqlmock.NewRows([]string{"id", "item_id", "address"})
-	rows.AddRow(1, 1, "guangzhou")
-	mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
-
-	rows = sqlmock.NewRows([]string{"id", "item_id", "address", "price", "user_name"})
-	rows.AddRow(1, 1, "guangzhou", 100, "alice")
-	mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
-
-	rows = sqlmock.NewRows([]string{"id", "item_id", "address", "price"})
-	rows.AddRow(1, 1, "guangzhou", 100)
-	mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+	totals := func() *Selector[Order] {
+		return NewSelector[Order](db).Select(C("UserId"), Sum(C("Amount")).As("total")).GroupBy(C("UserId"))
+	}
+	u := TableOf(&User{}).As("u")
 
 	testCases := []struct {
-		name    string
-		entity  any
-		s       *Selector[Result]
-		wantErr error
-		wantRes []*Result
+		name      string
+		s         QueryBuilder
+		wantQuery *Query
+		wantErr   error
 	}{
 		{
-			name: "struct fields more than query results",
-			s: func() *Selector[Result] {
-				t1 := TableOf(&Order{}).As("t1")
-				t2 := TableOf(&OrderDetail{}).As("t2")
-				t3 := t1.RightJoin(t2).On(t1.C("Id").Eq(t2.C("OrderId")))
-				return NewSelector[Result](db).
-					Select(t1.C("Id"), t2.C("ItemId"), t2.C("Price")).
-					From(t3)
+			name: "different model",
+			s: func() QueryBuilder {
+				o := totals().AsSubquery("o")
+				return NewSelector[Report](db).Select(o.C("UserId"), o.C("total")).From(o).Where(o.C("total").Gt(100))
 			}(),
-			entity:  &Result{},
-			wantErr: nil,
-			wantRes: []*Result{
-				&Result{
-					Id:      1,
-					ItemId:  1,
-					Address: "guangzhou",
-					Price:   0,
-				},
+			wantQuery: &Query{
+				SQL:  "SELECT o.user_id,o.total FROM (SELECT user_id,SUM(amount) AS total FROM order GROUP BY user_id) AS o WHERE o.total>?;",
+				Args: []any{100},
 			},
 		},
 		{
-			name: "struct fields less than query results",
-			s: func() *Selector[Result] {
-				t1 := TableOf(&Order{}).As("t1")
-				t2 := TableOf(&OrderDetail{}).As("t2")
-				t3 := t1.RightJoin(t2).On(t1.C("Id").Eq(t2.C("OrderId")))
-				return NewSelector[Result](db).
-					Select(t1.C("Id"), t2.C("ItemId"), t2.C("Price"), t2.C("Address"), t1.C("UserName")).
-					From(t3)
+			name: "generic subquery",
+			s: func() QueryBuilder {
+				o := SubqueryOf(totals()).As("o")
+				return NewSelector[Report](db).Select(o.C("total")).From(o)
 			}(),
-			entity:  &Result{},
-			wantErr: errs.NewUnknownColumn("user_name"),
+			wantQuery: &Query{
+				SQL: "SELECT o.total FROM (SELECT user_id,SUM(amount) AS total FROM order GROUP BY user_id) AS o;",
+			},
 		},
 		{
-			name: "default",
-			s: func() *Selector[Result] {
-				t1 := TableOf(&Order{}).As("t1")
-				t2 := TableOf(&OrderDetail{}).As("t2")
-				t3 := t1.RightJoin(t2).On(t1.C("Id").Eq(t2.C("OrderId")))
-				return NewSelector[Result](db).
-					Select(t1.C("Id"), t2.C("ItemId"), t2.C("Price"), t2.C("Address")).
-					From(t3).Where(t1.C("Id").Gt(100))
+			name: "left operand",
+			s: func() QueryBuilder {
+				o := totals().AsSubquery("o")
+				return NewSelector[Report](db).Select(u.C("Name"), o.C("total")).
+					From(o.Join(u).On(o.C("UserId").Eq(u.C("Id"))))
 			}(),
-			entity:  &Result{},
-			wantErr: nil,
-			wantRes: []*Result{
-				&Result{
-					Id:      1,
-					ItemId:  1,
-					Address: "guangzhou",
-					Price:   100,
-				},
+			wantQuery: &Query{
+				SQL: "SELECT u.name,o.total FROM ((SELECT user_id,SUM(amount) AS total FROM order GROUP BY user_id) AS o JOIN user AS u ON o.user_id=u.id);",
 			},
 		},
-	}
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-
-			res, err := tc.s.GetMulti(context.Background())
-			assert.Equal(t, tc.wantErr, err)
-			if err != nil {
-				return
-			}
-			assert.Equal(t, tc.wantRes, res)
-		})
-	}
+		{
+			name: "right operand",
+			s: func() QueryBuilder {
+				o := totals().AsSubquery("o")
+				return NewSelector[User](db).Select(u.C("Name"), o.C("total")).
+					From(u.LeftJoin(o).On(u.C("Id").Eq(o.C("UserId"))))
+			}(),
+			wantQuery: &Query{
+				SQL: "SELECT u.name,o.total FROM (user AS u LEFT JOIN (SELECT user_id,SUM(amount) AS total FROM order GROUP BY user_id) AS o ON u.id=o.user_id);",
+			},
+		},
+		{
+			name: "select all",
+			s: func() QueryBuilder {
+				o := NewSelector[Order](db).Where(C("Amount").Gt(0)).AsSubquery("o")
+				return NewSelector[Report](db).Select(o.C("UserId")).From(o)
+			}(),
+			wantQuery: &Query{
+				SQL:  "SELECT o.user_id FROM (SELECT * FROM order WHERE amount>?) AS o;",
+				Args: []any{0},
+			},
+		},
+		{
+			name: "column not selected",
+			s: func() QueryBuilder {
+				o := totals().AsSubquery("o")
+				return NewSelector[Report](db).Select(o.C("Amount")).From(o)
+			}(),
+			wantErr: errs.NewUnknownColumn("Amount"),
+		},
+		{
+			name: "aliased column by field name",
+			s: func() QueryBuilder {
+				o := NewSelector[Order](db).Select(C("UserId").As("uid")).AsSubquery("o")
+				return NewSelector[Report](db).Select(o.C("UserId")).From(o)
+			}(),
+			wantErr: errs.NewUnknownColumn("UserId"),
+		},
+		{
+			name: "raw expression",
+			s: func() QueryBuilder {
+				o := NewSelector[Order](db).Select(Raw("user_id, COUNT(*) AS cnt")).AsSubquery("o")
+				return NewSelector[Report](db).Select(o.C("UserId"), o.C("cnt")).From(o)
+			}(),
+			wantQuery: &Query{
+				SQL: "SELECT o.user_id,o.cnt FROM (SELECT user_id, COUNT(*) AS cnt FROM order) AS o;",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := tc.s.Build()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, q)
+		})
+	}
+}
+
+func TestSelector_Hint(t *testing.T) {
+	mysqlDB := memoryDB(t)
+	postgresDB := memoryDB(t, DBWithDialect(DialectPostgreSQL))
+	t1 := TableOf(&TestModel{}).As("t1")
+	t2 := TableOf(&TestModel{}).As("t2")
+
+	testCases := []struct {
+		name      string
+		s         QueryBuilder
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			name: "use index",
+			s:    NewSelector[TestModel](mysqlDB).UseIndex("idx_age").Where(C("Age").Gt(18)),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM test_model USE INDEX (idx_age) WHERE age>?;",
+				Args: []any{18},
+			},
+		},
+		{
+			name: "force and ignore index with alias",
+			s: NewSelector[TestModel](mysqlDB).From(t1).
+				ForceIndex("idx_age", "idx_name").IgnoreIndex("PRIMARY").Where(t1.C("Age").Gt(18)),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM test_model AS t1 FORCE INDEX (idx_age,idx_name) IGNORE INDEX (PRIMARY) WHERE t1.age>?;",
+				Args: []any{18},
+			},
+		},
+		{
+			name: "optimizer hint",
+			s:    NewSelector[TestModel](mysqlDB).OptimizerHint("MAX_EXECUTION_TIME(1000)").Select(C("Id")).Distinct(),
+			wantQuery: &Query{
+				SQL: "SELECT /*+ MAX_EXECUTION_TIME(1000) */ DISTINCT id FROM test_model;",
+			},
+		},
+		{
+			name:    "index hint on join",
+			s:       NewSelector[TestModel](mysqlDB).From(t1.Join(t2).On(t1.C("Id").Eq(t2.C("Id")))).UseIndex("idx_age"),
+			wantErr: errs.ErrIndexHintNotTable,
+		},
+		{
+			name:    "postgres index hint",
+			s:       NewSelector[TestModel](postgresDB).UseIndex("idx_age"),
+			wantErr: errs.ErrUnsupportedIndexHint,
+		},
+		{
+			name: "postgres optimizer hint ignored",
+			s:    NewSelector[TestModel](postgresDB).OptimizerHint("SeqScan(test_model)"),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM test_model;",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := tc.s.Build()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, q)
+		})
+	}
+}
+
+func TestSelector_WhereChain(t *testing.T) {
+	db := memoryDB(t)
+	name := ""
+
+	testCases := []struct {
+		name      string
+		s         QueryBuilder
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			name: "and where",
+			s:    NewSelector[TestModel](db).Where(C("Age").Gt(18)).AndWhere(C("Id").Lt(100), C("FirstName").Eq("Tom")),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM test_model WHERE ((age>?) AND (id<?)) AND (first_name=?);",
+				Args: []any{18, 100, "Tom"},
+			},
+		},
+		{
+			name: "or where",
+			s:    NewSelector[TestModel](db).Where(C("Age").Gt(18), C("Id").Lt(100)).OrWhere(C("FirstName").Eq("Tom")),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM test_model WHERE ((age>?) AND (id<?)) OR (first_name=?);",
+				Args: []any{18, 100, "Tom"},
+			},
+		},
+		{
+			name: "where if",
+			s: NewSelector[TestModel](db).WhereIf(name != "", C("FirstName").Eq(name)).
+				WhereIf(true, C("Age").Gt(18)),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM test_model WHERE age>?;",
+				Args: []any{18},
+			},
+		},
+		{
+			name: "where replaces",
+			s:    NewSelector[TestModel](db).AndWhere(C("Age").Gt(18)).Where(C("Id").Eq(1)),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM test_model WHERE id=?;",
+				Args: []any{1},
+			},
+		},
+		{
+			name: "variadic groups",
+			s: NewSelector[TestModel](db).Where(
+				Or(C("Age").Lt(18), C("Age").Gt(60)),
+				And(C("Id").Gt(1), C("Id").Lt(100)),
+			),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM test_model WHERE ((age<?) OR (age>?)) AND ((id>?) AND (id<?));",
+				Args: []any{18, 60, 1, 100},
+			},
+		},
+		{
+			name: "or where without previous",
+			s:    NewSelector[TestModel](db).OrWhere(C("Id").Eq(1)),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM test_model WHERE id=?;",
+				Args: []any{1},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := tc.s.Build()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, q)
+		})
+	}
+}
+
+func TestEmptyPredicate(t *testing.T) {
+	db := memoryDB(t)
+	pgDB := memoryDB(t, DBWithDialect(DialectPostgreSQL))
+	t1 := TableOf(&TestModel{}).As("t1")
+	t2 := TableOf(&KeyModel{}).As("t2")
+
+	testCases := []struct {
+		name      string
+		s         QueryBuilder
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			name: "empty groups",
+			s:    NewSelector[TestModel](db).Where(And(), Or(), Not(And())).OrWhere().Having(Or()),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM test_model;",
+			},
+		},
+		{
+			name: "empty group dropped",
+			s:    NewSelector[TestModel](db).Where(Or(), C("Age").Gt(18)).OrWhere(And(), C("Id").Eq(1)),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM test_model WHERE (age>?) OR (id=?);",
+				Args: []any{18, 1},
+			},
+		},
+		{
+			name: "empty on dropped",
+			s:    NewSelector[TestModel](db).From(t1.Join(t2).On(And(), t1.C("Id").Eq(t2.C("Id")), Or())),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM (test_model AS t1 JOIN key_model AS t2 ON t1.id=t2.id);",
+			},
+		},
+		{
+			name:    "empty on",
+			s:       NewSelector[TestModel](db).From(t1.LeftJoin(t2).On(And())),
+			wantErr: errs.ErrEmptyJoinOn,
+		},
+		{
+			name:    "delete empty where",
+			s:       NewDeletor[TestModel](db).Where(Or()),
+			wantErr: errs.ErrNoWhere,
+		},
+		{
+			name:    "update empty where",
+			s:       NewUpdater[TestModel](db).ValueMap(map[string]any{"Age": 18}).Where(And()).AndWhere(Or()),
+			wantErr: errs.ErrNoWhere,
+		},
+		{
+			name:    "update from empty on",
+			s:       NewUpdater[TestModel](pgDB).Table(t1.Join(t2).On(Or())).ValueMap(map[string]any{"Age": 18}).AllowFullTable(),
+			wantErr: errs.ErrEmptyJoinOn,
+		},
+		{
+			name:    "delete using empty on",
+			s:       NewDeletor[TestModel](pgDB).Table(t1.Join(t2).On(And())).Where(t2.C("Name").Eq("Tom")),
+			wantErr: errs.ErrEmptyJoinOn,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := tc.s.Build()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, q)
+		})
+	}
+}
+
+func TestSelector_AndWhereNotShared(t *testing.T) {
+	db := memoryDB(t)
+	base := NewSelector[TestModel](db).Where(C("Age").Gt(18))
+	derived := base.derive()
+	base.AndWhere(C("Id").Eq(1))
+	derived.AndWhere(C("Id").Eq(2))
+	assert.Equal(t, []Predicate{C("Age").Gt(18), C("Id").Eq(1)}, base.where)
+	assert.Equal(t, []Predicate{C("Age").Gt(18), C("Id").Eq(2)}, derived.where)
+}
+
+func TestSelector_BuildIdempotent(t *testing.T) {
+	db := memoryDB(t)
+	s := NewSelector[TestModel](db).Where(C("Age").Gt(18)).OrderBy(C("Id").Desc()).Limit(10)
+	q1, err := s.Build()
+	require.NoError(t, err)
+	q2, err := s.Build()
+	require.NoError(t, err)
+	assert.Equal(t, &Query{
+		SQL:  "SELECT * FROM test_model WHERE age>? ORDER BY id DESC LIMIT 10;",
+		Args: []any{18},
+	}, q2)
+	assert.Equal(t, q1, q2)
+
+	// 不在执行期间时不缓存，修改之后再 Build 拿到的是新语句
+	s.Where(C("Age").Lt(60))
+	q3, err := s.Build()
+	require.NoError(t, err)
+	assert.Equal(t, &Query{
+		SQL:  "SELECT * FROM test_model WHERE age<? ORDER BY id DESC LIMIT 10;",
+		Args: []any{60},
+	}, q3)
+}
+
+func TestSelector_Clone(t *testing.T) {
+	db := memoryDB(t)
+	base := NewSelector[TestModel](db).Where(C("Age").Gt(18)).UseIndex("idx_age")
+	adults := base.Clone().AndWhere(C("FirstName").Eq("Tom")).UseIndex("idx_name")
+	named := base.Clone().OrderBy(C("Id").Desc()).Limit(1)
+	// 先 Build 过的查询 Clone 出来也互不影响
+	_, err := base.Build()
+	require.NoError(t, err)
+	fromBuilt := base.Clone().AndWhere(C("Id").Eq(1))
+
+	testCases := []struct {
+		name    string
+		s       QueryBuilder
+		wantSQL string
+		args    []any
+	}{
+		{
+			name:    "base",
+			s:       base,
+			wantSQL: "SELECT * FROM test_model USE INDEX (idx_age) WHERE age>?;",
+			args:    []any{18},
+		},
+		{
+			name:    "and where",
+			s:       adults,
+			wantSQL: "SELECT * FROM test_model USE INDEX (idx_age) USE INDEX (idx_name) WHERE (age>?) AND (first_name=?);",
+			args:    []any{18, "Tom"},
+		},
+		{
+			name:    "order by",
+			s:       named,
+			wantSQL: "SELECT * FROM test_model USE INDEX (idx_age) WHERE age>? ORDER BY id DESC LIMIT 1;",
+			args:    []any{18},
+		},
+		{
+			name:    "clone after build",
+			s:       fromBuilt,
+			wantSQL: "SELECT * FROM test_model USE INDEX (idx_age) WHERE (age>?) AND (id=?);",
+			args:    []any{18, 1},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := tc.s.Build()
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantSQL, q.SQL)
+			assert.Equal(t, tc.args, q.Args)
+		})
+	}
+}
+
+// 多个中间件叠在一起时，每个中间件和最终执行拿到的都是同一条语句
+func TestSelector_MiddlewaresBuildOnce(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	var queries []*Query
+	record := func(next Handler) Handler {
+		return func(ctx context.Context, qc *QueryContext) *QueryResult {
+			q, err := qc.Builder.Build()
+			if err != nil {
+				return &QueryResult{Err: err}
+			}
+			queries = append(queries, q)
+			return next(ctx, qc)
+		}
+	}
+	db, err := OpenDB(mockDB, DBWithMiddlewares(record, record, record))
+	require.NoError(t, err)
+
+	wantSQL := "SELECT * FROM test_model WHERE age>?;"
+	rows := sqlmock.NewRows([]string{"id", "first_name", "age", "last_name"}).AddRow(1, "Tom", 18, "Jerry")
+	mock.ExpectQuery(regexp.QuoteMeta(wantSQL)).WithArgs(18).WillReturnRows(rows)
+	rows = sqlmock.NewRows([]string{"id", "first_name", "age", "last_name"}).AddRow(1, "Tom", 18, "Jerry")
+	mock.ExpectQuery(regexp.QuoteMeta(wantSQL)).WithArgs(18).WillReturnRows(rows)
+
+	s := NewSelector[TestModel](db).Where(C("Age").Gt(18))
+	for i := 0; i < 2; i++ {
+		queries = nil
+		_, err = s.Get(context.Background())
+		require.NoError(t, err)
+		require.Len(t, queries, 3)
+		assert.Equal(t, wantSQL, queries[0].SQL)
+		assert.Equal(t, []any{18}, queries[0].Args)
+		assert.Same(t, queries[0], queries[1])
+		assert.Same(t, queries[0], queries[2])
+	}
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// join 结果解析方式一：
+// 给最开始的 T 传 Result，要求构造 sql 的过程中所有出现列的地方都要带上表名
+func TestSelectorJoin_GetMulti(t *testing.T) {
+	type Order struct {
+		Id        int
+		UserName  string
+		UsingCol1 string
+		UsingCol2 string
+	}
+
+	type OrderDetail struct {
+		OrderId int
+		ItemId  int
+		Address string
+		Price   int
+	}
+
+	type Result struct {
+		Id      int
+		ItemId  int
+		Address string
+		Price   int
+	}
+
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+
+	rows := sqlmock.NewRows([]string{"id", "item_id", "address"})
+	rows.AddRow(1, 1, "guangzhou")
+	mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+
+	rows = sqlmock.NewRows([]string{"id", "item_id", "address", "price", "user_name"})
+	rows.AddRow(1, 1, "guangzhou", 100, "alice")
+	mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+
+	rows = sqlmock.NewRows([]string{"id", "item_id", "address", "price"})
+	rows.AddRow(1, 1, "guangzhou", 100)
+	mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+
+	testCases := []struct {
+		name    string
+		entity  any
+		s       *Selector[Result]
+		wantErr error
+		wantRes []*Result
+	}{
+		{
+			name: "struct fields more than query results",
+			s: func() *Selector[Result] {
+				t1 := TableOf(&Order{}).As("t1")
+				t2 := TableOf(&OrderDetail{}).As("t2")
+				t3 := t1.RightJoin(t2).On(t1.C("Id").Eq(t2.C("OrderId")))
+				return NewSelector[Result](db).
+					Select(t1.C("Id"), t2.C("ItemId"), t2.C("Price")).
+					From(t3)
+			}(),
+			entity:  &Result{},
+			wantErr: nil,
+			wantRes: []*Result{
+				&Result{
+					Id:      1,
+					ItemId:  1,
+					Address: "guangzhou",
+					Price:   0,
+				},
+			},
+		},
+		{
+			name: "struct fields less than query results",
+			s: func() *Selector[Result] {
+				t1 := TableOf(&Order{}).As("t1")
+				t2 := TableOf(&OrderDetail{}).As("t2")
+				t3 := t1.RightJoin(t2).On(t1.C("Id").Eq(t2.C("OrderId")))
+				return NewSelector[Result](db).
+					Select(t1.C("Id"), t2.C("ItemId"), t2.C("Price"), t2.C("Address"), t1.C("UserName")).
+					From(t3)
+			}(),
+			entity:  &Result{},
+			wantErr: errs.NewUnknownColumn("user_name"),
+		},
+		{
+			name: "default",
+			s: func() *Selector[Result] {
+				t1 := TableOf(&Order{}).As("t1")
+				t2 := TableOf(&OrderDetail{}).As("t2")
+				t3 := t1.RightJoin(t2).On(t1.C("Id").Eq(t2.C("OrderId")))
+				return NewSelector[Result](db).
+					Select(t1.C("Id"), t2.C("ItemId"), t2.C("Price"), t2.C("Address")).
+					From(t3).Where(t1.C("Id").Gt(100))
+			}(),
+			entity:  &Result{},
+			wantErr: nil,
+			wantRes: []*Result{
+				&Result{
+					Id:      1,
+					ItemId:  1,
+					Address: "guangzhou",
+					Price:   100,
+				},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+
+			res, err := tc.s.GetMulti(context.Background())
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
+
+func TestSelector_Count(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name      string
+		s         *Selector[TestModel]
+		wantQuery string
+		wantArgs  []driver.Value
+		wantRes   int64
+	}{
+		{
+			name:      "count",
+			s:         NewSelector[TestModel](db).Where(C("Age").Gt(18)).OrderBy(C("Id").Desc()).Limit(10),
+			wantQuery: "SELECT COUNT(*) FROM test_model WHERE age>?;",
+			wantArgs:  []driver.Value{18},
+			wantRes:   3,
+		},
+		{
+			name:      "count group",
+			s:         NewSelector[TestModel](db).Where(C("Age").Gt(18)).GroupBy(C("Age")),
+			wantQuery: "SELECT COUNT(*) FROM (SELECT age FROM test_model WHERE age>? GROUP BY age) AS t;",
+			wantArgs:  []driver.Value{18},
+			wantRes:   2,
+		},
+		{
+			name:      "count distinct",
+			s:         NewSelector[TestModel](db).Select(C("Age")).Where(C("Age").Gt(18)).Distinct(),
+			wantQuery: "SELECT COUNT(*) FROM (SELECT DISTINCT age FROM test_model WHERE age>?) AS t;",
+			wantArgs:  []driver.Value{18},
+			wantRes:   2,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rows := sqlmock.NewRows([]string{"COUNT(*)"})
+			rows.AddRow(tc.wantRes)
+			mock.ExpectQuery(regexp.QuoteMeta(tc.wantQuery)).WithArgs(tc.wantArgs...).WillReturnRows(rows)
+			res, err := tc.s.Count(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSelector_OrderByNulls(t *testing.T) {
+	mockDB, _, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB, DBWithDialect(DialectSQLite))
+	require.NoError(t, err)
+
+	q, err := NewSelector[TestModel](db).
+		OrderBy(C("LastName").Asc().NullsLast(), Desc(Raw("LENGTH(first_name)")).NullsFirst()).Build()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM test_model ORDER BY last_name ASC NULLS LAST,(LENGTH(first_name)) DESC NULLS FIRST;", q.SQL)
+}
+
+func TestSelector_DistinctOn(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB, DBWithDialect(DialectPostgreSQL))
+	require.NoError(t, err)
+
+	q, err := NewSelector[TestModel](db).DistinctOn(C("FirstName")).
+		OrderBy(C("FirstName").Asc(), C("Age").Desc()).Build()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT DISTINCT ON (first_name) * FROM test_model ORDER BY first_name ASC,age DESC;", q.SQL)
+
+	rows := sqlmock.NewRows([]string{"id", "first_name", "age", "last_name"})
+	rows.AddRow(1, "Tom", 18, nil)
+	rows.AddRow(3, "Jerry", 20, nil)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT ON (first_name) * FROM test_model WHERE (age>$1) AND (id<$2) ORDER BY first_name ASC,age DESC;`)).
+		WithArgs(10, 100).WillReturnRows(rows)
+	res, err := NewSelector[TestModel](db).DistinctOn(C("FirstName")).Where(C("Age").Gt(10), C("Id").Lt(100)).
+		OrderBy(C("FirstName").Asc(), C("Age").Desc()).GetMulti(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []*TestModel{{Id: 1, FirstName: "Tom", Age: 18}, {Id: 3, FirstName: "Jerry", Age: 20}}, res)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgreSQL_Placeholder(t *testing.T) {
+	db := memoryDB(t, DBWithDialect(DialectPostgreSQL))
+	type Order struct {
+		Id     int
+		UserId int
+	}
+
+	testCases := []struct {
+		name      string
+		s         QueryBuilder
+		wantQuery *Query
+	}{
+		{
+			name: "subquery",
+			s: func() QueryBuilder {
+				t1 := TableOf(&TestModel{}).As("t1")
+				o := SubqueryOf(NewSelector[Order](db).Where(C("Id").Gt(1))).As("o")
+				return NewSelector[TestModel](db).From(t1.Join(o).On(t1.C("Id").Eq(o.C("UserId")), o.C("Id").Lt(100))).
+					Where(t1.C("Age").Gt(18))
+			}(),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM (test_model AS t1 JOIN (SELECT * FROM order WHERE id>$1) AS o ON (t1.id=o.user_id) AND (o.id<$2)) WHERE t1.age>$3;",
+				Args: []any{1, 100, 18},
+			},
+		},
+		{
+			name: "quoted question mark",
+			s:    NewSelector[TestModel](db).Where(Raw(`first_name<>'?' AND "age"<>'it''s ?'`).AsPredicate(), C("Id").Eq(1)),
+			wantQuery: &Query{
+				SQL:  `SELECT * FROM test_model WHERE ((first_name<>'?' AND "age"<>'it''s ?')) AND (id=$1);`,
+				Args: []any{1},
+			},
+		},
+		{
+			name: "upsert",
+			s: NewInserter[TestModel](db).Values(&TestModel{Id: 1, FirstName: "Tom", Age: 18}).
+				Upsert().ConflictColumns("Id").Update(Assign("Age", 10)),
+			wantQuery: &Query{
+				SQL:  "INSERT INTO test_model (id,first_name,age,last_name) VALUES ($1,$2,$3,$4) ON CONFLICT(id) DO UPDATE SET age=$5;",
+				Args: []any{int64(1), "Tom", int8(18), (*sql.NullString)(nil), 10},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := tc.s.Build()
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantQuery, q)
+		})
+	}
+}
+
+func TestSelector_Exists(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+
+	rows := sqlmock.NewRows([]string{"1"})
+	rows.AddRow(1)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT 1 FROM test_model WHERE id=? LIMIT 1;")).WithArgs(1).WillReturnRows(rows)
+	ok, err := NewSelector[TestModel](db).Where(C("Id").Eq(1)).Exists(context.Background())
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT 1 FROM test_model WHERE id=? LIMIT 1;")).WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"1"}))
+	ok, err = NewSelector[TestModel](db).Where(C("Id").Eq(2)).Exists(context.Background())
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	mock.ExpectQuery("SELECT .*").WillReturnError(errors.New("db error"))
+	ok, err = NewSelector[TestModel](db).Exists(context.Background())
+	assert.Equal(t, errors.New("db error"), err)
+	assert.False(t, ok)
+}
+
+func TestPluck(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+
+	rows := sqlmock.NewRows([]string{"first_name"})
+	rows.AddRow("Tom")
+	rows.AddRow("Jerry")
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT first_name FROM test_model WHERE age>? ORDER BY id DESC LIMIT 2;")).
+		WithArgs(18).WillReturnRows(rows)
+	res, err := Pluck[string](context.Background(),
+		NewSelector[TestModel](db).Where(C("Age").Gt(18)).OrderBy(C("Id").Desc()).Limit(2), C("FirstName"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Tom", "Jerry"}, res)
+
+	_, err = Pluck[string](context.Background(), NewSelector[TestModel](db), C("XXX"))
+	assert.Equal(t, errs.NewUnknownField("XXX"), err)
+
+	// 加锁子句要带到派生的查询上
+	mock.ExpectBegin()
+	tx, err := db.BeginTx(context.Background(), nil)
+	require.NoError(t, err)
+	rows = sqlmock.NewRows([]string{"first_name"})
+	rows.AddRow("Tom")
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT first_name FROM test_model WHERE age>? FOR UPDATE SKIP LOCKED;")).
+		WithArgs(18).WillReturnRows(rows)
+	res, err = Pluck[string](context.Background(),
+		NewSelector[TestModel](tx).Where(C("Age").Gt(18)).ForUpdate().SkipLocked(), C("FirstName"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Tom"}, res)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAggregateValue(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+
+	rows := sqlmock.NewRows([]string{"SUM(age)"})
+	rows.AddRow(36)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT SUM(age) FROM test_model WHERE id>?;")).
+		WithArgs(1).WillReturnRows(rows)
+	res, err := AggregateValue[int64](context.Background(),
+		NewSelector[TestModel](db).Where(C("Id").Gt(1)).GroupBy(C("Age")).OrderBy(C("Age").Asc()), Sum(C("Age")))
+	require.NoError(t, err)
+	assert.Equal(t, int64(36), res)
+
+	rows = sqlmock.NewRows([]string{"MAX(age)"})
+	rows.AddRow(nil)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT MAX(age) FROM test_model;")).WillReturnRows(rows)
+	max, err := AggregateValue[sql.NullInt64](context.Background(), NewSelector[TestModel](db), Max(C("Age")))
+	require.NoError(t, err)
+	assert.False(t, max.Valid)
+}
+
+func TestSelector_Lock(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	mock.ExpectBegin()
+	mock.ExpectBegin()
+	mock.ExpectBegin()
+	newTx := func(opts ...DBOption) *Tx {
+		db, err := OpenDB(mockDB, opts...)
+		require.NoError(t, err)
+		tx, err := db.BeginTx(context.Background(), nil)
+		require.NoError(t, err)
+		return tx
+	}
+	mysqlTx := newTx()
+	sqliteTx := newTx(DBWithDialect(DialectSQLite))
+	ignoreTx := newTx(DBWithDialect(DialectSQLite), DBWithIgnoreUnsupportedLock())
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name      string
+		s         QueryBuilder
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			name: "for update skip locked",
+			s:    NewSelector[TestModel](mysqlTx).Where(C("Age").Eq(0)).Limit(10).ForUpdate().SkipLocked(),
+			wantQuery: &Query{
+				SQL:  "SELECT * FROM test_model WHERE age=? LIMIT 10 FOR UPDATE SKIP LOCKED;",
+				Args: []any{0},
+			},
+		},
+		{
+			name: "for share nowait",
+			s:    NewSelector[TestModel](mysqlTx).ForShare().NoWait(),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM test_model FOR SHARE NOWAIT;",
+			},
+		},
+		{
+			// Build 只生成语句，在事务外执行时才会报错
+			name: "build outside tx",
+			s:    NewSelector[TestModel](db).ForUpdate(),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM test_model FOR UPDATE;",
+			},
+		},
+		{
+			name:    "sqlite unsupported",
+			s:       NewSelector[TestModel](sqliteTx).ForUpdate(),
+			wantErr: errs.ErrUnsupportedLock,
+		},
+		{
+			name: "sqlite ignore",
+			s:    NewSelector[TestModel](ignoreTx).ForUpdate().SkipLocked(),
+			wantQuery: &Query{
+				SQL: "SELECT * FROM test_model;",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := tc.s.Build()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, q)
+		})
+	}
+}
+
+func TestSelector_LockOutsideTx(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+	ctx := context.Background()
+	s := NewSelector[TestModel](db).Where(C("Id").Eq(1)).ForUpdate()
+
+	_, err = s.Get(ctx)
+	assert.Equal(t, errs.ErrLockOutsideTx, err)
+	_, err = s.GetMulti(ctx)
+	assert.Equal(t, errs.ErrLockOutsideTx, err)
+	_, err = s.Iter(ctx)
+	assert.Equal(t, errs.ErrLockOutsideTx, err)
+	_, err = Project[int64](NewSelector[TestModel](db).Select(C("Id")).ForShare()).GetMulti(ctx)
+	assert.Equal(t, errs.ErrLockOutsideTx, err)
+	_, err = ScanJoin[TestModel](ctx, s)
+	assert.Equal(t, errs.ErrLockOutsideTx, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSelector_MemoizeReentrant(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	nested := false
+	var s *Selector[TestModel]
+	mdl := func(next Handler) Handler {
+		return func(ctx context.Context, qc *QueryContext) *QueryResult {
+			q1, err := qc.Builder.Build()
+			require.NoError(t, err)
+			if !nested {
+				// 在中间件里用同一个 Selector 再查一次
+				nested = true
+				_, err = s.Get(ctx)
+				require.NoError(t, err)
+			}
+			q2, err := qc.Builder.Build()
+			require.NoError(t, err)
+			assert.Same(t, q1, q2)
+			return next(ctx, qc)
+		}
+	}
+	db, err := OpenDB(mockDB, DBWithMiddlewares(mdl))
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		rows := sqlmock.NewRows([]string{"id"})
+		rows.AddRow(1)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM test_model WHERE id=?;")).WithArgs(1).WillReturnRows(rows)
+	}
+	s = NewSelector[TestModel](db).Where(C("Id").Eq(1))
+	_, err = s.Get(context.Background())
+	require.NoError(t, err)
+	assert.False(t, s.memoing)
+	assert.Nil(t, s.memo)
+	require.NoError(t, mock.ExpectationsWereMet())
 }
@@ -1,5 +1,10 @@
 package orm
 
+import (
+	"gitee.com/youkelike/orm/internal/errs"
+	"gitee.com/youkelike/orm/model"
+)
+
 type TableReference interface {
 	table()
 }
@@ -33,102 +38,224 @@ func (t Table) C(name string) Column {
 
 func (t Table) table() {}
 
-func (t Table) Join(right TableReference) *JoinBuilder {
-	return &JoinBuilder{
-		left:  t,
-		right: right,
-		typ:   "JOIN",
+// 列名前面的限定名，有别名用别名，没有就用表名
+func (t Table) prefix(m *model.Model) string {
+	if t.alias != "" {
+		return t.alias
 	}
+	return m.TableName
+}
+
+func (t Table) Join(right TableReference) *JoinBuilder {
+	return newJoinBuilder(t, right, "JOIN")
 }
 func (t Table) LeftJoin(right TableReference) *JoinBuilder {
-	return &JoinBuilder{
-		left:  t,
-		right: right,
-		typ:   "LEFT JOIN",
-	}
+	return newJoinBuilder(t, right, "LEFT JOIN")
 }
 func (t Table) RightJoin(right TableReference) *JoinBuilder {
-	return &JoinBuilder{
-		left:  t,
-		right: right,
-		typ:   "RIGHT JOIN",
-	}
+	return newJoinBuilder(t, right, "RIGHT JOIN")
+}
+func (t Table) FullJoin(right TableReference) *JoinBuilder {
+	return newJoinBuilder(t, right, "FULL JOIN")
+}
+func (t Table) CrossJoin(right TableReference) Join {
+	return Join{left: t, right: right, typ: "CROSS JOIN"}
+}
+func (t Table) NaturalJoin(right TableReference) Join {
+	return Join{left: t, right: right, typ: "NATURAL JOIN"}
+}
+func (t Table) JoinLateral(right TableReference) *JoinBuilder {
+	return newLateralJoinBuilder(t, right, "JOIN")
+}
+func (t Table) LeftJoinLateral(right TableReference) *JoinBuilder {
+	return newLateralJoinBuilder(t, right, "LEFT JOIN")
+}
+func (t Table) CrossJoinLateral(right TableReference) Join {
+	return Join{left: t, right: right, typ: "CROSS JOIN", lateral: true}
 }
 
 type Join struct {
 	left  TableReference
 	right TableReference
 	typ   string
-	on    []Predicate
-	using []string
+	// 为 true 时右边的表是 LATERAL 子查询，可以引用左边的表的列
+	lateral bool
+	on      []Predicate
+	using   []string
 }
 
 func (j Join) table() {}
 
 func (j Join) Join(right TableReference) *JoinBuilder {
-	return &JoinBuilder{
-		left:  j,
-		right: right,
-		typ:   "JOIN",
-	}
+	return newJoinBuilder(j, right, "JOIN")
 }
 func (j Join) LeftJoin(right TableReference) *JoinBuilder {
-	return &JoinBuilder{
-		left:  j,
-		right: right,
-		typ:   "LEFT JOIN",
-	}
+	return newJoinBuilder(j, right, "LEFT JOIN")
 }
 func (j Join) RightJoin(right TableReference) *JoinBuilder {
-	return &JoinBuilder{
-		left:  j,
-		right: right,
-		typ:   "RIGHT JOIN",
+	return newJoinBuilder(j, right, "RIGHT JOIN")
+}
+func (j Join) FullJoin(right TableReference) *JoinBuilder {
+	return newJoinBuilder(j, right, "FULL JOIN")
+}
+func (j Join) CrossJoin(right TableReference) Join {
+	return Join{left: j, right: right, typ: "CROSS JOIN"}
+}
+func (j Join) NaturalJoin(right TableReference) Join {
+	return Join{left: j, right: right, typ: "NATURAL JOIN"}
+}
+func (j Join) JoinLateral(right TableReference) *JoinBuilder {
+	return newLateralJoinBuilder(j, right, "JOIN")
+}
+func (j Join) LeftJoinLateral(right TableReference) *JoinBuilder {
+	return newLateralJoinBuilder(j, right, "LEFT JOIN")
+}
+func (j Join) CrossJoinLateral(right TableReference) Join {
+	return Join{left: j, right: right, typ: "CROSS JOIN", lateral: true}
+}
+
+// SubqueryTable 是不带类型参数的子查询，外层查询的模型可以和子查询的模型不同，
+// 例如 NewSelector[Report](db).From(NewSelector[Order](db).AsSubquery("o"))
+type SubqueryTable struct {
+	src subquerySource
+	as  string
+}
+
+// 子查询的来源，目前只有 Selector
+type subquerySource interface {
+	QueryBuilder
+	// 子查询结果集中可以被外层引用的列，key 是 C(name) 中的 name，value 是结果集中的列名。
+	// 第二个返回值为 false 表示没法确定结果集中有哪些列，比如 join 的 SELECT * 和原生表达式
+	outputColumns() (map[string]string, bool, error)
+}
+
+// Subquery 和 SubqueryTable 都可以用在 FROM 和 join 中
+type derivedTable interface {
+	TableReference
+	derived() SubqueryTable
+}
+
+func (s SubqueryTable) table() {}
+
+func (s SubqueryTable) derived() SubqueryTable {
+	return s
+}
+
+func (s SubqueryTable) As(name string) SubqueryTable {
+	s.as = name
+	return s
+}
+
+// 子查询的列，name 必须是子查询选中的列的字段名或者别名，生成 sql 时用子查询的别名限定
+func (s SubqueryTable) C(name string) Column {
+	return Column{
+		name:  name,
+		table: s,
+	}
+}
+
+// 把 C(name) 中的 name 转换成子查询结果集中的列名，确定不了有哪些列时原样使用
+func (s SubqueryTable) column(name string) (string, error) {
+	cols, known, err := s.src.outputColumns()
+	if err != nil {
+		return "", err
+	}
+	if col, ok := cols[name]; ok {
+		return col, nil
 	}
+	if !known {
+		return name, nil
+	}
+	return "", errs.NewUnknownColumn(name)
 }
 
+func (s SubqueryTable) Join(right TableReference) *JoinBuilder {
+	return newJoinBuilder(s, right, "JOIN")
+}
+func (s SubqueryTable) LeftJoin(right TableReference) *JoinBuilder {
+	return newJoinBuilder(s, right, "LEFT JOIN")
+}
+func (s SubqueryTable) RightJoin(right TableReference) *JoinBuilder {
+	return newJoinBuilder(s, right, "RIGHT JOIN")
+}
+func (s SubqueryTable) FullJoin(right TableReference) *JoinBuilder {
+	return newJoinBuilder(s, right, "FULL JOIN")
+}
+func (s SubqueryTable) CrossJoin(right TableReference) Join {
+	return Join{left: s, right: right, typ: "CROSS JOIN"}
+}
+func (s SubqueryTable) NaturalJoin(right TableReference) Join {
+	return Join{left: s, right: right, typ: "NATURAL JOIN"}
+}
+func (s SubqueryTable) JoinLateral(right TableReference) *JoinBuilder {
+	return newLateralJoinBuilder(s, right, "JOIN")
+}
+func (s SubqueryTable) LeftJoinLateral(right TableReference) *JoinBuilder {
+	return newLateralJoinBuilder(s, right, "LEFT JOIN")
+}
+func (s SubqueryTable) CrossJoinLateral(right TableReference) Join {
+	return Join{left: s, right: right, typ: "CROSS JOIN", lateral: true}
+}
+
+// Subquery 保留了子查询的模型类型，C 和 join 相关的方法都来自 SubqueryTable
 type Subquery[T any] struct {
-	builder *Selector[T]
-	as      string
+	SubqueryTable
 }
 
 func SubqueryOf[T any](s *Selector[T]) Subquery[T] {
 	return Subquery[T]{
-		builder: s,
+		SubqueryTable: SubqueryTable{src: s},
 	}
 }
 
-func (s Subquery[T]) table() {}
-
 func (s Subquery[T]) As(name string) Subquery[T] {
-	return Subquery[T]{
-		builder: s.builder,
-		as:      name,
-	}
+	s.as = name
+	return s
 }
 
 // 这是个中间对象，完整的 join 语句还有后面的 on，
 // 不要它的话，上面的 Join、LeftJoin、RightJoin 这些方法里就要加上与 on 相关的参数，调用过程不够简洁
 type JoinBuilder struct {
-	left  TableReference
-	right TableReference
-	typ   string
+	left    TableReference
+	right   TableReference
+	typ     string
+	lateral bool
+}
+
+func newJoinBuilder(left, right TableReference, typ string) *JoinBuilder {
+	return &JoinBuilder{
+		left:  left,
+		right: right,
+		typ:   typ,
+	}
+}
+
+// LATERAL 只能用在子查询上，right 不是子查询时生成 sql 会返回错误
+func newLateralJoinBuilder(left, right TableReference, typ string) *JoinBuilder {
+	return &JoinBuilder{
+		left:    left,
+		right:   right,
+		typ:     typ,
+		lateral: true,
+	}
 }
 
 func (j *JoinBuilder) On(ps ...Predicate) Join {
 	return Join{
-		left:  j.left,
-		right: j.right,
-		typ:   j.typ,
-		on:    ps,
+		left:    j.left,
+		right:   j.right,
+		typ:     j.typ,
+		lateral: j.lateral,
+		on:      ps,
 	}
 }
 
 func (j *JoinBuilder) Using(cols ...string) Join {
 	return Join{
-		left:  j.left,
-		right: j.right,
-		typ:   j.typ,
-		using: cols,
+		left:    j.left,
+		right:   j.right,
+		typ:     j.typ,
+		lateral: j.lateral,
+		using:   cols,
 	}
 }
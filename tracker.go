@@ -0,0 +1,96 @@
+package orm
+
+import (
+	"reflect"
+	"sync"
+
+	"gitee.com/youkelike/orm/internal/valuer"
+	"gitee.com/youkelike/orm/model"
+)
+
+// Tracker 记录实体加载时各字段的值（快照），更新时只更新和快照相比发生了变化的列，
+// 避免把别人并发修改的其他列覆盖掉。
+// 快照以实体指针为 key 保存，Tracker 的生命周期由使用者控制，用完了就丢掉，或者用 Forget 移除单个实体
+//
+//	tracker := NewTracker()
+//	u, err := NewSelector[User](db).Track(tracker).Where(C("Id").Eq(1)).Get(ctx)
+//	u.Name = "Tom"
+//	NewUpdater[User](db).Track(tracker).Value(u).Where(C("Id").Eq(1)).Exec(ctx)
+type Tracker struct {
+	mu        sync.RWMutex
+	snapshots map[any]map[string]any
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{
+		snapshots: make(map[any]map[string]any),
+	}
+}
+
+// Forget 不再跟踪这个实体
+func (t *Tracker) Forget(entity any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.snapshots, entity)
+}
+
+// 记录实体当前的快照，已经有快照的会被覆盖
+func (t *Tracker) track(m *model.Model, val valuer.Value, entity any) error {
+	snapshot := make(map[string]any, len(m.Fields))
+	for _, fd := range m.Fields {
+		v, err := val.Field(fd.GoName)
+		if err != nil {
+			return err
+		}
+		snapshot[fd.GoName] = snapshotValue(v)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.snapshots[entity] = snapshot
+	return nil
+}
+
+// 返回和快照相比发生了变化的非主键字段，实体没有被跟踪时第二个返回值为 false
+func (t *Tracker) changed(m *model.Model, val valuer.Value, entity any) ([]*model.Field, bool, error) {
+	t.mu.RLock()
+	snapshot, ok := t.snapshots[entity]
+	t.mu.RUnlock()
+	if !ok {
+		return nil, false, nil
+	}
+	var fds []*model.Field
+	for _, fd := range m.Fields {
+		if fd.PrimaryKey {
+			continue
+		}
+		v, err := val.Field(fd.GoName)
+		if err != nil {
+			return nil, true, err
+		}
+		if !reflect.DeepEqual(snapshot[fd.GoName], snapshotValue(v)) {
+			fds = append(fds, fd)
+		}
+	}
+	return fds, true, nil
+}
+
+// 指针和切片字段可能被原地修改，快照里要保存它们指向的内容的副本
+func snapshotValue(v any) any {
+	val := reflect.ValueOf(v)
+	switch val.Kind() {
+	case reflect.Pointer:
+		if val.IsNil() {
+			return nil
+		}
+		return val.Elem().Interface()
+	case reflect.Slice:
+		if val.IsNil() {
+			return v
+		}
+		cp := reflect.MakeSlice(val.Type(), val.Len(), val.Len())
+		reflect.Copy(cp, val)
+		return cp.Interface()
+	default:
+		return v
+	}
+}
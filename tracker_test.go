@@ -0,0 +1,65 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+
+	"gitee.com/youkelike/orm/internal/errs"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracker(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	tracker := NewTracker()
+	rows := sqlmock.NewRows([]string{"id", "first_name", "age", "last_name"})
+	rows.AddRow(1, "Tom", 18, "Jerry")
+	rows.AddRow(2, "Bob", 20, nil)
+	mock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+	tms, err := NewSelector[TestModel](db).Track(tracker).GetMulti(ctx)
+	require.NoError(t, err)
+
+	// 没有变化
+	res := NewUpdater[TestModel](db).Track(tracker).Value(tms[0]).Where(C("Id").Eq(1)).Exec(ctx)
+	assert.Equal(t, errs.ErrNoUpdateColumns, res.Err())
+
+	// 只更新变化了的列，指针字段原地修改也能发现
+	tms[0].Age = 19
+	tms[0].LastName.String = "Mark"
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE test_model SET age=?,last_name=? WHERE id=?;")).
+		WithArgs(int8(19), &sql.NullString{String: "Mark", Valid: true}, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	res = NewUpdater[TestModel](db).Track(tracker).Value(tms[0]).Where(C("Id").Eq(1)).Exec(ctx)
+	require.NoError(t, res.Err())
+
+	// 更新成功后刷新快照
+	tms[0].FirstName = "Alice"
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE test_model SET first_name=? WHERE id=?;")).
+		WithArgs("Alice", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	res = NewUpdater[TestModel](db).Track(tracker).Value(tms[0]).Where(C("Id").Eq(1)).Exec(ctx)
+	require.NoError(t, res.Err())
+
+	// nil 指针变成非 nil
+	tms[1].LastName = &sql.NullString{}
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE test_model SET last_name=? WHERE id=?;")).
+		WithArgs(&sql.NullString{}, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	res = NewUpdater[TestModel](db).Track(tracker).Value(tms[1]).Where(C("Id").Eq(2)).Exec(ctx)
+	require.NoError(t, res.Err())
+
+	// 不再跟踪后按 OmitZero 处理
+	tracker.Forget(tms[1])
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE test_model SET id=?,first_name=?,age=?,last_name=? WHERE id=?;")).
+		WithArgs(int64(2), "Bob", int8(20), &sql.NullString{}, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	res = NewUpdater[TestModel](db).Track(tracker).OmitZero().Value(tms[1]).Where(C("Id").Eq(2)).Exec(ctx)
+	require.NoError(t, res.Err())
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
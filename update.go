@@ -15,23 +15,44 @@ type Updater[T any] struct {
 
 	table string
 
-	value   *T
-	updates []Column
-	where   []Predicate
+	// 多表更新时的表，必须包含模型对应的表
+	tableRef TableReference
+
+	value *T
+	// 以字段名或列名为 key 的更新数据，设置了就不再使用 value
+	valueMap map[string]any
+	updates  []Column
+	where    []Predicate
+	orderBy  []OrderBy
+	limit    int
+	// 允许不带 WHERE 条件更新全表
+	allowFullTable bool
+
+	// 只更新和快照相比变化了的列
+	tracker *Tracker
+	// 只更新非零值的列
+	omitZero bool
+	// 优化器提示，生成在 UPDATE 后面
+	hint string
 }
 
 func NewUpdater[T any](sess Session) *Updater[T] {
 	c := sess.getCore()
 	return &Updater[T]{
 		builder: builder{
-			r:      c.r,
-			quoter: c.dialect.quoter(),
+			r:       c.r,
+			dialect: c.dialect,
+			quoter:  c.dialect.quoter(),
 		},
 		sess: sess,
 	}
 }
 
 func (d *Updater[T]) Build() (*Query, error) {
+	if d.memo != nil {
+		return d.memo, nil
+	}
+	d.reset()
 	if d.model == nil {
 		var err error
 		d.model, err = d.r.Get(new(T))
@@ -40,36 +61,81 @@ func (d *Updater[T]) Build() (*Query, error) {
 		}
 	}
 
+	syntax := d.sess.getCore().dialect.mutation()
+	where := d.where
+	// UPDATE ... FROM 后面的表
+	var from TableReference
+	// 多表时 SET 中的列要带上限定名，避免和其他表的列重名
+	prefix := ""
 	d.sb.WriteString("UPDATE ")
-	if d.table == "" {
+	d.buildOptimizerHint(d.sess.getCore().dialect, d.hint)
+	switch {
+	case d.tableRef != nil && syntax.inlineJoin:
+		target, err := d.mutationTarget(d.tableRef)
+		if err != nil {
+			return nil, err
+		}
+		prefix = target.prefix(d.model)
+		if err = d.buildTable(d.sess.getCore().dialect, d.tableRef); err != nil {
+			return nil, err
+		}
+	case d.tableRef != nil:
+		target, rest, on, err := d.splitMutationJoin(d.tableRef)
+		if err != nil {
+			return nil, err
+		}
+		if err = d.buildTable(d.sess.getCore().dialect, target); err != nil {
+			return nil, err
+		}
+		from = rest
+		where = append(on[:len(on):len(on)], d.where...)
+	case d.table == "":
 		d.sb.WriteString(d.model.TableName)
-	} else {
+	default:
 		d.sb.WriteString(d.table)
 	}
 
 	d.sb.WriteString(" SET ")
-	var fdList []*model.Field
-	if len(d.updates) > 0 { // 更新指定列
-		for _, col := range d.updates {
-			fd, ok := d.model.FieldMap[col.name]
-			if !ok {
-				return nil, errs.NewUnknownField(col.name)
-			}
-			fdList = append(fdList, fd)
+	if err := d.buildSet(prefix); err != nil {
+		return nil, err
+	}
+	if from != nil {
+		d.sb.WriteString(" FROM ")
+		if err := d.buildTable(d.sess.getCore().dialect, from); err != nil {
+			return nil, err
 		}
-	} else { // 更新所有列
-		fdList = d.model.Fields
+	}
+	if And(d.where...).empty() && !d.allowFullTable {
+		return nil, errs.ErrNoWhere
+	}
+	if err := d.buildWhere(where); err != nil {
+		return nil, err
+	}
+	if err := d.buildOrderLimit(d.sess.getCore().dialect, d.tableRef != nil, d.orderBy, d.limit); err != nil {
+		return nil, err
+	}
+	d.sb.WriteString(";")
+
+	return d.done(), nil
+}
+
+func (d *Updater[T]) buildSet(prefix string) error {
+	if d.valueMap != nil {
+		return d.buildValueMap(prefix)
 	}
 	if d.value == nil {
-		return nil, errs.NewUnknownUpdateValue()
+		return errs.NewUnknownUpdateValue()
+	}
+	fdList, err := d.updateFields()
+	if err != nil {
+		return err
 	}
 	for i, fd := range fdList {
 		if i > 0 {
 			d.sb.WriteString(",")
 		}
 		val := reflect.ValueOf(d.value).Elem().FieldByName(fd.GoName).Interface()
-		d.sb.WriteString(fd.ColName)
-		d.sb.WriteString("=?")
+		d.buildSetColumn(prefix, fd.ColName)
 		// if v, ok := val.(*sql.NullString); ok {
 		// 	d.addArgs(v.String)
 		// } else {
@@ -77,24 +143,94 @@ func (d *Updater[T]) Build() (*Query, error) {
 		// }
 		d.addArgs(val)
 	}
+	return nil
+}
 
-	if d.where != nil {
-		d.sb.WriteString(" WHERE ")
-		p := d.where[0]
-		for i := 1; i < len(d.where); i++ {
-			p = p.And(d.where[i])
+func (d *Updater[T]) buildSetColumn(prefix, col string) {
+	if prefix != "" {
+		d.sb.WriteString(prefix)
+		d.sb.WriteString(".")
+	}
+	d.sb.WriteString(col)
+	d.sb.WriteString("=?")
+}
+
+// 按模型中字段定义的顺序生成 SET 子句，保证生成的 sql 是稳定的
+func (d *Updater[T]) buildValueMap(prefix string) error {
+	if len(d.valueMap) == 0 {
+		return errs.ErrNoUpdateColumns
+	}
+	vals, err := d.fieldValues(d.valueMap)
+	if err != nil {
+		return err
+	}
+	cnt := 0
+	for _, fd := range d.model.Fields {
+		val, ok := vals[fd]
+		if !ok {
+			continue
+		}
+		if cnt > 0 {
+			d.sb.WriteString(",")
 		}
+		d.buildSetColumn(prefix, fd.ColName)
+		d.addArgs(val)
+		cnt++
+	}
+	return nil
+}
 
-		if err := d.buildPredicate(p); err != nil {
+// 确定要更新的列，优先级：指定的列、和快照相比变化了的列、非零值的列、所有列，
+// 后面三种情况下主键不会被更新
+func (d *Updater[T]) updateFields() ([]*model.Field, error) {
+	if len(d.updates) > 0 { // 更新指定列
+		fdList := make([]*model.Field, 0, len(d.updates))
+		for _, col := range d.updates {
+			fd, ok := d.model.FieldMap[col.name]
+			if !ok {
+				return nil, errs.NewUnknownField(col.name)
+			}
+			fdList = append(fdList, fd)
+		}
+		return fdList, nil
+	}
+
+	val := d.sess.getCore().creator(d.model, d.value)
+	if d.tracker != nil {
+		fdList, ok, err := d.tracker.changed(d.model, val, d.value)
+		if err != nil {
 			return nil, err
 		}
+		if ok {
+			if len(fdList) == 0 {
+				return nil, errs.ErrNoUpdateColumns
+			}
+			return fdList, nil
+		}
 	}
-	d.sb.WriteString(";")
 
-	return &Query{
-		SQL:  d.sb.String(),
-		Args: d.args,
-	}, nil
+	if d.omitZero {
+		var fdList []*model.Field
+		for _, fd := range d.model.Fields {
+			if fd.PrimaryKey {
+				continue
+			}
+			v, err := val.Field(fd.GoName)
+			if err != nil {
+				return nil, err
+			}
+			if !isZero(v) {
+				fdList = append(fdList, fd)
+			}
+		}
+		if len(fdList) == 0 {
+			return nil, errs.ErrNoUpdateColumns
+		}
+		return fdList, nil
+	}
+
+	// 更新所有列
+	return d.model.Fields, nil
 }
 
 func (d *Updater[T]) From(table string) *Updater[T] {
@@ -102,21 +238,109 @@ func (d *Updater[T]) From(table string) *Updater[T] {
 	return d
 }
 
+// Table 指定多表更新的表，table 中必须包含模型对应的表，只会更新这张表的列。
+// mysql 生成 UPDATE a JOIN b ON ... SET ...；
+// postgres、sqlite 生成 UPDATE a SET ... FROM b WHERE ...，此时模型对应的表必须是 join 最左边的表，
+// 并且和后面的表是内连接，连接条件会被挪到 WHERE 中
+//
+//	o := TableOf(&Order{})
+//	NewUpdater[Order](db).Table(o.Join(TableOf(&User{})).On(o.C("UserId").Eq(...)))
+func (d *Updater[T]) Table(table TableReference) *Updater[T] {
+	d.tableRef = table
+	return d
+}
+
+// OrderBy 和 Limit 只有单表更新可以用，mysql 和 sqlite 支持，postgres 不支持
+func (d *Updater[T]) OrderBy(items ...Orderable) *Updater[T] {
+	d.orderBy = orderBys(items)
+	return d
+}
+
+func (d *Updater[T]) Limit(limit int) *Updater[T] {
+	d.limit = limit
+	return d
+}
+
+// OptimizerHint 在 UPDATE 后面生成 /*+ hint */，不支持的方言会忽略
+func (d *Updater[T]) OptimizerHint(hint string) *Updater[T] {
+	d.hint = hint
+	return d
+}
+
 func (d *Updater[T]) Value(val *T) *Updater[T] {
 	d.value = val
 	return d
 }
 
+// ValueMap 用 map 指定要更新的列和值，key 可以是字段名也可以是列名，
+// 适合根据 PATCH 请求体之类的动态数据做更新
+func (d *Updater[T]) ValueMap(vals map[string]any) *Updater[T] {
+	d.valueMap = vals
+	return d
+}
+
 func (d *Updater[T]) Updates(columns ...Column) *Updater[T] {
 	d.updates = columns
 	return d
 }
 
+// Track 实体被这个 Tracker 跟踪时，只更新和快照相比变化了的列，更新成功后刷新快照；
+// 没被跟踪时按 OmitZero 或者更新所有列处理
+func (d *Updater[T]) Track(t *Tracker) *Updater[T] {
+	d.tracker = t
+	return d
+}
+
+// OmitZero 只更新非零值的列，适合用新构造的结构体做部分更新
+func (d *Updater[T]) OmitZero() *Updater[T] {
+	d.omitZero = true
+	return d
+}
+
+// AllowFullTable 没有 WHERE 条件时默认返回 errs.ErrNoWhere，确实要更新全表时需要显式调用这个方法
+func (d *Updater[T]) AllowFullTable() *Updater[T] {
+	d.allowFullTable = true
+	return d
+}
+
+// Where 会替换掉之前设置的条件，分步骤构造条件时用 AndWhere、OrWhere、WhereIf
 func (d *Updater[T]) Where(ps ...Predicate) *Updater[T] {
 	d.where = ps
 	return d
 }
 
+// AndWhere 追加条件，和之前的条件用 AND 连接
+func (d *Updater[T]) AndWhere(ps ...Predicate) *Updater[T] {
+	d.where = andWhere(d.where, ps)
+	return d
+}
+
+// OrWhere 把之前的条件作为一个整体，和 ps 用 OR 连接，ps 之间是 AND：
+// Where(a, b).OrWhere(c, d) 生成 (a AND b) OR (c AND d)
+func (d *Updater[T]) OrWhere(ps ...Predicate) *Updater[T] {
+	d.where = orWhere(d.where, ps)
+	return d
+}
+
+// WhereIf 在 cond 为 true 时追加条件，相当于 AndWhere
+func (d *Updater[T]) WhereIf(cond bool, ps ...Predicate) *Updater[T] {
+	if cond {
+		return d.AndWhere(ps...)
+	}
+	return d
+}
+
+// Clone 复制出一个独立的 Updater，之后对任意一方调用的方法都不会影响另一方。
+// Value、ValueMap 传入的数据和 Track 的 Tracker 仍然是共用的
+func (d *Updater[T]) Clone() *Updater[T] {
+	c := *d
+	c.builder = d.builder.clone()
+	c.updates = append([]Column(nil), d.updates...)
+	c.where = append([]Predicate(nil), d.where...)
+	c.orderBy = append([]OrderBy(nil), d.orderBy...)
+	return &c
+}
+
 func (d *Updater[T]) Exec(ctx context.Context) Result {
 	var err error
 	d.model, err = d.r.Get(new(T))
@@ -137,6 +361,16 @@ func (d *Updater[T]) Exec(ctx context.Context) Result {
 	if res.Result != nil {
 		sqlRes = res.Result.(sql.Result)
 	}
+	// 只更新了部分指定列时，其他列可能还有没保存的修改，不能刷新快照
+	if res.Err == nil && d.tracker != nil && d.value != nil && d.valueMap == nil && len(d.updates) == 0 {
+		err = d.tracker.track(d.model, d.sess.getCore().creator(d.model, d.value), d.value)
+		if err != nil {
+			return Result{
+				err: err,
+				res: sqlRes,
+			}
+		}
+	}
 
 	return Result{
 		err: res.Err,
@@ -1,8 +1,11 @@
 package orm
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
+	"regexp"
 	"testing"
 
 	"gitee.com/youkelike/orm/internal/errs"
@@ -31,6 +34,20 @@ func TestBuildUpdater(t *testing.T) {
 		wantQuery *Query
 		wantErr   error
 	}{
+		{
+			// Clone 之后修改原来的 Updater 不影响复制出来的
+			name: "clone",
+			builder: func() QueryBuilder {
+				base := NewUpdater[TestModel](db).ValueMap(map[string]any{"Age": 18}).Where(C("Age").Lt(18))
+				c := base.Clone().AndWhere(C("FirstName").Eq("Tom"))
+				base.Where(C("Id").Eq(1)).Limit(1)
+				return c
+			}(),
+			wantQuery: &Query{
+				SQL:  "UPDATE test_model SET age=? WHERE (age<?) AND (first_name=?);",
+				Args: []any{18, 18, "Tom"},
+			},
+		},
 		{
 			name:    "invalid value",
 			builder: NewUpdater[TestModel](db).Where(C("FirstName").Eq("Tom").Or(C("XXX").Eq(1))),
@@ -38,7 +55,7 @@ func TestBuildUpdater(t *testing.T) {
 		},
 		{
 			name:    "value",
-			builder: NewUpdater[TestModel](db).Value(tm),
+			builder: NewUpdater[TestModel](db).Value(tm).AllowFullTable(),
 			wantQuery: &Query{
 				SQL:  "UPDATE test_model SET id=?,first_name=?,age=?,last_name=?;",
 				Args: []any{int64(1), "zs", int8(18), (*sql.NullString)(nil)},
@@ -60,6 +77,53 @@ func TestBuildUpdater(t *testing.T) {
 				Args: []any{int8(18), "zs", "Tom"},
 			},
 		},
+		{
+			name:    "omit zero",
+			builder: NewUpdater[TestModel](db).Value(&TestModel{FirstName: "Tom"}).OmitZero().Where(C("Id").Eq(1)),
+			wantQuery: &Query{
+				SQL:  "UPDATE test_model SET first_name=? WHERE id=?;",
+				Args: []any{"Tom", 1},
+			},
+		},
+		{
+			name:    "omit zero all zero",
+			builder: NewUpdater[TestModel](db).Value(&TestModel{}).OmitZero(),
+			wantErr: errs.ErrNoUpdateColumns,
+		},
+		{
+			name: "value map",
+			builder: NewUpdater[TestModel](db).ValueMap(map[string]any{
+				"age":       20,
+				"FirstName": "Tom",
+			}).Where(C("Id").Eq(1)),
+			wantQuery: &Query{
+				SQL:  "UPDATE test_model SET first_name=?,age=? WHERE id=?;",
+				Args: []any{"Tom", 20, 1},
+			},
+		},
+		{
+			name:    "value map unknown key",
+			builder: NewUpdater[TestModel](db).ValueMap(map[string]any{"xxx": 1}),
+			wantErr: errs.NewUnknownField("xxx"),
+		},
+		{
+			name: "value map duplicate key",
+			builder: NewUpdater[TestModel](db).ValueMap(map[string]any{
+				"age": 20,
+				"Age": 21,
+			}),
+			wantErr: errs.NewDuplicateField("Age"),
+		},
+		{
+			name:    "value map empty",
+			builder: NewUpdater[TestModel](db).ValueMap(map[string]any{}),
+			wantErr: errs.ErrNoUpdateColumns,
+		},
+		{
+			name:    "no where",
+			builder: NewUpdater[TestModel](db).Value(tm),
+			wantErr: errs.ErrNoWhere,
+		},
 		{
 			name:    "updates invalid",
 			builder: NewUpdater[TestModel](db).Value(tm).Updates(C("XXX")).Where(C("FirstName").Eq("Tom")),
@@ -77,3 +141,149 @@ func TestBuildUpdater(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdater_Join(t *testing.T) {
+	mockDB, _, err := sqlmock.New()
+	require.NoError(t, err)
+	mysqlDB, err := OpenDB(mockDB)
+	require.NoError(t, err)
+	sqliteDB, err := OpenDB(mockDB, DBWithDialect(DialectSQLite))
+	require.NoError(t, err)
+	pgDB, err := OpenDB(mockDB, DBWithDialect(DialectPostgreSQL))
+	require.NoError(t, err)
+
+	t1 := TableOf(&TestModel{}).As("t1")
+	t2 := TableOf(&KeyModel{}).As("t2")
+	t3 := TableOf(&CompositeKeyModel{})
+	join := t1.Join(t2).On(t1.C("Id").Eq(t2.C("Id")))
+
+	testCases := []struct {
+		name      string
+		builder   QueryBuilder
+		wantQuery *Query
+		wantErr   error
+	}{
+		{
+			name: "mysql join",
+			builder: NewUpdater[TestModel](mysqlDB).Table(join).
+				ValueMap(map[string]any{"Age": 18}).Where(t2.C("Name").Eq("Tom")),
+			wantQuery: &Query{
+				SQL:  "UPDATE (test_model AS t1 JOIN key_model AS t2 ON t1.id=t2.id) SET t1.age=? WHERE t2.name=?;",
+				Args: []any{18, "Tom"},
+			},
+		},
+		{
+			name: "mysql target not found",
+			builder: NewUpdater[TestModel](mysqlDB).Table(TableOf(&KeyModel{})).
+				ValueMap(map[string]any{"Age": 18}),
+			wantErr: errs.ErrMutationTargetNotFound,
+		},
+		{
+			name: "mysql join order by",
+			builder: NewUpdater[TestModel](mysqlDB).Table(join).
+				ValueMap(map[string]any{"Age": 18}).Where(t2.C("Name").Eq("Tom")).Limit(10),
+			wantErr: errs.NewUnsupportedMutation("ORDER BY 和 LIMIT"),
+		},
+		{
+			name: "mysql order by limit",
+			builder: NewUpdater[TestModel](mysqlDB).ValueMap(map[string]any{"Age": 18}).
+				Where(C("Age").Lt(18)).OrderBy(C("Id").Asc()).Limit(10),
+			wantQuery: &Query{
+				SQL:  "UPDATE test_model SET age=? WHERE age<? ORDER BY id ASC LIMIT 10;",
+				Args: []any{18, 18},
+			},
+		},
+		{
+			name: "sqlite from",
+			builder: NewUpdater[TestModel](sqliteDB).Table(join).
+				ValueMap(map[string]any{"Age": 18}).Where(t2.C("Name").Eq("Tom")),
+			wantQuery: &Query{
+				SQL:  "UPDATE test_model AS t1 SET age=? FROM key_model AS t2 WHERE (t1.id=t2.id) AND (t2.name=?);",
+				Args: []any{18, "Tom"},
+			},
+		},
+		{
+			name: "postgres from many tables",
+			builder: NewUpdater[TestModel](pgDB).
+				Table(join.Join(t3).On(t2.C("Id").Eq(t3.C("UserId")))).
+				ValueMap(map[string]any{"Age": 18}).AllowFullTable(),
+			wantQuery: &Query{
+				SQL:  "UPDATE test_model AS t1 SET age=$1 FROM (key_model AS t2 JOIN composite_key_model ON t2.id=composite_key_model.user_id) WHERE t1.id=t2.id;",
+				Args: []any{18},
+			},
+		},
+		{
+			name: "postgres left join",
+			builder: NewUpdater[TestModel](pgDB).Table(t1.LeftJoin(t2).On(t1.C("Id").Eq(t2.C("Id")))).
+				ValueMap(map[string]any{"Age": 18}),
+			wantErr: errs.NewUnsupportedMutation("LEFT JOIN"),
+		},
+		{
+			name: "postgres target not leftmost",
+			builder: NewUpdater[TestModel](pgDB).Table(t2.Join(t1).On(t1.C("Id").Eq(t2.C("Id")))).
+				ValueMap(map[string]any{"Age": 18}),
+			wantErr: errs.ErrMutationTargetNotFound,
+		},
+		{
+			name: "mysql optimizer hint",
+			builder: NewUpdater[TestModel](mysqlDB).Table(join).OptimizerHint("NO_MERGE(t2)").
+				ValueMap(map[string]any{"Age": 18}).Where(t2.C("Name").Eq("Tom")),
+			wantQuery: &Query{
+				SQL:  "UPDATE /*+ NO_MERGE(t2) */ (test_model AS t1 JOIN key_model AS t2 ON t1.id=t2.id) SET t1.age=? WHERE t2.name=?;",
+				Args: []any{18, "Tom"},
+			},
+		},
+		{
+			name: "postgres optimizer hint ignored",
+			builder: NewUpdater[TestModel](pgDB).OptimizerHint("NO_MERGE(t2)").
+				ValueMap(map[string]any{"Age": 18}).Where(C("Id").Eq(1)),
+			wantQuery: &Query{
+				SQL:  "UPDATE test_model SET age=$1 WHERE id=$2;",
+				Args: []any{18, 1},
+			},
+		},
+		{
+			name: "mysql where chain",
+			builder: NewUpdater[TestModel](mysqlDB).ValueMap(map[string]any{"Age": 18}).
+				Where(C("Id").Gt(1)).WhereIf(false, C("Age").Lt(18)).OrWhere(C("FirstName").Eq("Tom"), C("Age").Eq(0)),
+			wantQuery: &Query{
+				SQL:  "UPDATE test_model SET age=? WHERE (id>?) OR ((first_name=?) AND (age=?));",
+				Args: []any{18, 1, "Tom", 0},
+			},
+		},
+		{
+			name: "postgres limit",
+			builder: NewUpdater[TestModel](pgDB).ValueMap(map[string]any{"Age": 18}).
+				Where(C("Age").Lt(18)).Limit(10),
+			wantErr: errs.NewUnsupportedMutation("ORDER BY 和 LIMIT"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := tc.builder.Build()
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantQuery, res)
+		})
+	}
+}
+
+func TestUpdater_PostgreSQLFrom(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := OpenDB(mockDB, DBWithDialect(DialectPostgreSQL))
+	require.NoError(t, err)
+
+	t1 := TableOf(&TestModel{}).As("t1")
+	t2 := TableOf(&KeyModel{}).As("t2")
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE test_model AS t1 SET age=$1 FROM key_model AS t2 WHERE (t1.id=t2.id) AND (t2.age>$2);")).
+		WithArgs(18, 20).WillReturnResult(driver.RowsAffected(3))
+	affected, err := NewUpdater[TestModel](db).Table(t1.Join(t2).On(t1.C("Id").Eq(t2.C("Id")))).
+		ValueMap(map[string]any{"Age": 18}).
+		Where(t2.C("Age").Gt(20)).Exec(context.Background()).RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), affected)
+	require.NoError(t, mock.ExpectationsWereMet())
+}